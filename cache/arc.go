@@ -0,0 +1,121 @@
+package cache
+
+import "time"
+
+const (
+	segmentT1 segment = iota // entries seen once recently
+	segmentT2                // entries seen at least twice recently
+)
+
+// ARC is a thread-safe Adaptive Replacement Cache.
+// Resident entries live in T1 (recency) and T2 (frequency), keys of evicted entries are kept in ghost lists B1 and B2.
+// A ghost hit in B1 means T1 was too small, a ghost hit in B2 means T2 was too small, and the target size p of T1
+// moves towards the list which missed, so the cache adapts to the workload without tuning.
+type ARC[K comparable, V any] struct {
+	segmented[K, V]
+	capacity int
+	p        int // target size of T1
+	b1       *ghostList[K]
+	b2       *ghostList[K]
+}
+
+// NewARC creates ARC cache which holds up to capacity entries and remembers up to capacity evicted keys.
+// ttl is the default time to live for entries, zero ttl means entries never expire.
+func NewARC[K comparable, V any](capacity int, ttl time.Duration) *ARC[K, V] {
+	capacity = max(capacity, 1)
+
+	return &ARC[K, V]{
+		segmented: newSegmented[K, V](ttl, capacity, capacity),
+		capacity:  capacity,
+		b1:        newGhostList[K](capacity),
+		b2:        newGhostList[K](capacity),
+	}
+}
+
+func (c *ARC[K, V]) Get(k K) (V, bool) {
+	c.mu.Lock()
+	elem, expired := c.lookup(k)
+	if elem == nil {
+		c.unlock(expired, EvictExpired)
+		c.countGet(false)
+		var zeroVal V
+		return zeroVal, false
+	}
+
+	// any hit makes the entry frequent
+	c.relink(elem, segmentT2)
+	v := elem.val.val
+	c.mu.Unlock()
+	c.countGet(true)
+	return v, true
+}
+
+func (c *ARC[K, V]) Set(k K, v V) {
+	c.mu.Lock()
+	expiresAt := c.expiresAt()
+	if elem, exists := c.items[k]; exists {
+		elem.val.val = v
+		elem.val.expiresAt = expiresAt
+		c.relink(elem, segmentT2)
+		c.mu.Unlock()
+		return
+	}
+
+	t1 := c.segments[segmentT1]
+	ent := segmentEntry[K, V]{key: k, val: v, expiresAt: expiresAt}
+
+	var evicted []segmentEntry[K, V]
+	switch {
+	case c.b1.contains(k):
+		// recency list was too small, grow its target
+		c.p = min(c.capacity, c.p+max(c.b2.len()/c.b1.len(), 1))
+		evicted = c.replace(false)
+		c.b1.remove(k)
+		c.push(segmentT2, ent)
+
+	case c.b2.contains(k):
+		// frequency list was too small, shrink recency target
+		c.p = max(0, c.p-max(c.b1.len()/c.b2.len(), 1))
+		evicted = c.replace(true)
+		c.b2.remove(k)
+		c.push(segmentT2, ent)
+
+	default:
+		if t1.len+c.b1.len() >= c.capacity {
+			if t1.len < c.capacity {
+				c.b1.removeOldest()
+				evicted = c.replace(false)
+			} else {
+				evicted = []segmentEntry[K, V]{c.removeElement(t1.back())}
+			}
+		} else if total := len(c.items) + c.b1.len() + c.b2.len(); total >= c.capacity {
+			if total >= 2*c.capacity {
+				c.b2.removeOldest()
+			}
+			if len(c.items) >= c.capacity {
+				evicted = c.replace(false)
+			}
+		}
+		c.push(segmentT1, ent)
+	}
+	c.unlock(evicted, EvictCapacity)
+}
+
+// replace evicts one entry from T1 or T2 to its ghost list depending on the target p, must be called with c.mu held.
+// inB2 tells whether the key being inserted was found in B2.
+func (c *ARC[K, V]) replace(inB2 bool) []segmentEntry[K, V] {
+	if len(c.items) < c.capacity {
+		return nil
+	}
+
+	t1, t2 := c.segments[segmentT1], c.segments[segmentT2]
+	if t1.len > 0 && (t1.len > c.p || (inB2 && t1.len == c.p) || t2.len == 0) {
+		ent := c.removeElement(t1.back())
+		c.b1.add(ent.key)
+		return []segmentEntry[K, V]{ent}
+	}
+
+	ent := c.removeElement(t2.back())
+	c.b2.add(ent.key)
+	return []segmentEntry[K, V]{ent}
+}
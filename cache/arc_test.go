@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestARC(t *testing.T) {
+	t.Run("Set Get Delete", func(t *testing.T) {
+		c := NewARC[string, int](10, 0)
+
+		c.Set("a", 1)
+		if v, ok := c.Get("a"); !ok || v != 1 {
+			t.Fatalf("expected a = 1, got %d (%v)", v, ok)
+		}
+		if !c.Delete("a") || c.Len() != 0 {
+			t.Fatal("expected a to be deleted")
+		}
+	})
+
+	t.Run("Hit moves entry to T2", func(t *testing.T) {
+		c := NewARC[int, int](4, 0)
+
+		c.Set(1, 1)
+		c.Get(1)
+		for i := 2; i <= 10; i++ {
+			c.Set(i, i)
+		}
+
+		// frequent entry survives the stream of new ones
+		if _, ok := c.Peek(1); !ok {
+			t.Fatal("expected frequent entry to stay in cache")
+		}
+		if c.Len() != 4 {
+			t.Fatalf("expected len 4, got %d", c.Len())
+		}
+	})
+
+	t.Run("Ghost hit adapts target", func(t *testing.T) {
+		c := NewARC[int, int](4, 0)
+
+		for i := 0; i < 4; i++ {
+			c.Set(i, i)
+			c.Get(i)
+		}
+		for i := 4; i < 8; i++ {
+			c.Set(i, i)
+		}
+
+		if c.b1.len() == 0 && c.b2.len() == 0 {
+			t.Fatal("expected evicted keys in ghost lists")
+		}
+
+		p := c.p
+		for i := 4; i < 8; i++ {
+			if c.b1.contains(i) {
+				c.Set(i, i)
+				break
+			}
+		}
+		if c.p <= p {
+			t.Fatalf("expected B1 ghost hit to grow p from %d, got %d", p, c.p)
+		}
+	})
+
+	t.Run("Capacity and TTL", func(t *testing.T) {
+		c := NewARC[string, int](20, time.Millisecond)
+		clock := NewManualClock(time.Now())
+		c.SetClock(clock)
+
+		evicted := map[EvictReason]int{}
+		c.OnEvict(func(k string, v int, reason EvictReason) { evicted[reason]++ })
+
+		for i := 0; i < 200; i++ {
+			key := fmt.Sprintf("key-%d", i%50)
+			if _, ok := c.Get(key); !ok {
+				c.Set(key, i)
+			}
+			if c.Len() > 20 {
+				t.Fatalf("cache exceeded capacity: %d", c.Len())
+			}
+		}
+
+		clock.Advance(5 * time.Millisecond)
+		for i := 0; i < 50; i++ {
+			c.Get(fmt.Sprintf("key-%d", i))
+		}
+		if c.Len() != 0 || evicted[EvictExpired] == 0 || evicted[EvictCapacity] == 0 {
+			t.Fatalf("expected all entries to expire, len %d, evictions %v", c.Len(), evicted)
+		}
+	})
+}
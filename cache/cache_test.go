@@ -11,21 +11,32 @@ import (
 type cacheVal struct {
 	val       interface{}
 	expiresAt time.Time
+	expiry    *expiryItem[string]
 }
 
+// cleanupBatch is the max number of entries removed under one lock
+const cleanupBatch = 128
+
 type Cache struct {
 	mu      sync.Mutex
 	cache   map[string]*cacheVal
+	expiry  expiryIndex[string]
 	ttl     time.Duration
+	clock   Clock
 	context context.Context
 	cancel  context.CancelFunc
 }
 
 func NewCache(ttl time.Duration) *Cache {
+	return newCacheWithClock(ttl, systemClock{})
+}
+
+func newCacheWithClock(ttl time.Duration, clock Clock) *Cache {
 	ctx, cancel := context.WithCancel(context.Background())
 	cache := &Cache{
 		cache:   make(map[string]*cacheVal),
 		ttl:     ttl,
+		clock:   clock,
 		context: ctx,
 		cancel:  cancel,
 	}
@@ -41,13 +52,8 @@ func (c *Cache) cleanup() {
 	for {
 		select {
 		case <-ticker.C:
-			c.mu.Lock()
-			for k, v := range c.cache {
-				if time.Now().After(v.expiresAt) {
-					delete(c.cache, k)
-				}
+			for c.removeExpired(cleanupBatch) == cleanupBatch {
 			}
-			c.mu.Unlock()
 		case <-c.context.Done():
 			fmt.Println("context done")
 			return
@@ -55,28 +61,55 @@ func (c *Cache) cleanup() {
 	}
 }
 
+// removeExpired removes up to limit due entries and returns number of removed ones
+func (c *Cache) removeExpired(limit int) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := c.expiry.popDue(c.clock.Now(), limit)
+	for _, k := range keys {
+		delete(c.cache, k)
+	}
+	return len(keys)
+}
+
 func (c *Cache) Set(k string, v interface{}) {
 	c.mu.Lock()
+	expiresAt := c.clock.Now().Add(c.ttl)
+	var item *expiryItem[string]
+	if old, ok := c.cache[k]; ok {
+		item = old.expiry
+	}
 	c.cache[k] = &cacheVal{
 		val:       v,
-		expiresAt: time.Now().Add(c.ttl),
+		expiresAt: expiresAt,
+		expiry:    c.expiry.set(item, k, expiresAt),
 	}
 	c.mu.Unlock()
 }
 
 func (c *Cache) Get(k string) (interface{}, bool) {
 	c.mu.Lock()
-	if v, ok := c.cache[k]; ok {
-		c.mu.Unlock()
-		return v.val, true
+	defer c.mu.Unlock()
+
+	v, ok := c.cache[k]
+	if !ok {
+		return nil, false
 	}
-	c.mu.Unlock()
-	return nil, false
+	if c.clock.Now().After(v.expiresAt) {
+		c.expiry.remove(v.expiry)
+		delete(c.cache, k)
+		return nil, false
+	}
+	return v.val, true
 }
 
 func (c *Cache) Delete(k string) {
 	c.mu.Lock()
-	delete(c.cache, k)
+	if v, ok := c.cache[k]; ok {
+		c.expiry.remove(v.expiry)
+		delete(c.cache, k)
+	}
 	c.mu.Unlock()
 }
 
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Clock tells the current time, caches use it for TTL so tests can drive time without sleeping
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// ManualClock is a Clock which moves only when Set or Advance is called
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func NewManualClock(now time.Time) *ManualClock {
+	return &ManualClock{now: now}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	c.now = now
+	c.mu.Unlock()
+}
+
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// expiryItem is a position of a key in the expiry index, entries keep it to update or remove the key in O(log n)
+type expiryItem[K comparable] struct {
+	key       K
+	expiresAt time.Time
+	index     int // position in the heap, -1 when the item is not in the heap
+}
+
+// expiryHeap is a min-heap of expiration times which implements heap.Interface
+type expiryHeap[K comparable] []*expiryItem[K]
+
+func (h expiryHeap[K]) Len() int {
+	return len(h)
+}
+
+func (h expiryHeap[K]) Less(i, j int) bool {
+	return h[i].expiresAt.Before(h[j].expiresAt)
+}
+
+func (h expiryHeap[K]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expiryHeap[K]) Push(x interface{}) {
+	item := x.(*expiryItem[K])
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expiryHeap[K]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	lastElem := old[n-1]
+	old[n-1] = nil // avoid memory leak
+	lastElem.index = -1
+	*h = old[:n-1]
+	return lastElem
+}
+
+// expiryIndex orders keys with TTL by expiration time, so cleanup visits only due keys
+// instead of scanning the whole cache. It is not thread-safe and is guarded by the cache lock.
+type expiryIndex[K comparable] struct {
+	h expiryHeap[K]
+}
+
+// set adds the key to the index or moves it to the new expiration time.
+// Zero expiresAt removes the key, as such entries never expire.
+func (x *expiryIndex[K]) set(item *expiryItem[K], k K, expiresAt time.Time) *expiryItem[K] {
+	if expiresAt.IsZero() {
+		x.remove(item)
+		return nil
+	}
+
+	if item == nil || item.index < 0 {
+		item = &expiryItem[K]{key: k, expiresAt: expiresAt}
+		heap.Push(&x.h, item)
+		return item
+	}
+
+	item.expiresAt = expiresAt
+	heap.Fix(&x.h, item.index)
+	return item
+}
+
+func (x *expiryIndex[K]) remove(item *expiryItem[K]) {
+	if item == nil || item.index < 0 {
+		return
+	}
+	heap.Remove(&x.h, item.index)
+}
+
+// popDue removes up to limit keys which have expired at now and returns them
+func (x *expiryIndex[K]) popDue(now time.Time, limit int) []K {
+	var keys []K
+	for len(keys) < limit && len(x.h) > 0 && now.After(x.h[0].expiresAt) {
+		keys = append(keys, heap.Pop(&x.h).(*expiryItem[K]).key)
+	}
+	return keys
+}
+
+func (x *expiryIndex[K]) len() int {
+	return len(x.h)
+}
@@ -0,0 +1,319 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExpiryIndex(t *testing.T) {
+	t.Run("Pops only due keys in expiration order", func(t *testing.T) {
+		var x expiryIndex[string]
+		now := time.Unix(1000, 0)
+
+		x.set(nil, "c", now.Add(3*time.Second))
+		x.set(nil, "a", now.Add(time.Second))
+		x.set(nil, "later", now.Add(time.Hour))
+		x.set(nil, "b", now.Add(2*time.Second))
+
+		keys := x.popDue(now.Add(5*time.Second), 10)
+		if fmt.Sprint(keys) != "[a b c]" {
+			t.Fatalf("expected [a b c], got %v", keys)
+		}
+		if x.len() != 1 {
+			t.Fatalf("expected 1 key left, got %d", x.len())
+		}
+	})
+
+	t.Run("Respects the limit", func(t *testing.T) {
+		var x expiryIndex[int]
+		now := time.Unix(1000, 0)
+		for i := 0; i < 10; i++ {
+			x.set(nil, i, now)
+		}
+
+		if keys := x.popDue(now.Add(time.Second), 3); len(keys) != 3 {
+			t.Fatalf("expected 3 keys, got %v", keys)
+		}
+		if x.len() != 7 {
+			t.Fatalf("expected 7 keys left, got %d", x.len())
+		}
+	})
+
+	t.Run("Set moves and removes the key", func(t *testing.T) {
+		var x expiryIndex[string]
+		now := time.Unix(1000, 0)
+
+		a := x.set(nil, "a", now.Add(time.Second))
+		b := x.set(nil, "b", now.Add(2*time.Second))
+		a = x.set(a, "a", now.Add(time.Hour))
+
+		if keys := x.popDue(now.Add(time.Minute), 10); fmt.Sprint(keys) != "[b]" {
+			t.Fatalf("expected [b], got %v", keys)
+		}
+		if b.index != -1 {
+			t.Fatalf("expected popped item to be detached, got index %d", b.index)
+		}
+
+		if a = x.set(a, "a", time.Time{}); a != nil || x.len() != 0 {
+			t.Fatalf("expected zero time to remove the key, %d keys left", x.len())
+		}
+	})
+}
+
+func TestLRUExpiry(t *testing.T) {
+	t.Run("Expires on read", func(t *testing.T) {
+		clock := NewManualClock(time.Unix(1000, 0))
+		lru := NewLRU[string, int](0, time.Minute)
+		lru.SetClock(clock)
+
+		lru.Set("a", 1)
+		clock.Advance(59 * time.Second)
+		if _, ok := lru.Get("a"); !ok {
+			t.Fatal("expected a to be alive before TTL")
+		}
+
+		clock.Advance(2 * time.Second)
+		if _, ok := lru.Peek("a"); ok {
+			t.Fatal("expected Peek to hide expired a")
+		}
+		if _, ok := lru.Get("a"); ok {
+			t.Fatal("expected a to be expired")
+		}
+		if lru.Len() != 0 {
+			t.Fatalf("expected expired entry to be removed on read, len %d", lru.Len())
+		}
+		if n := lru.expiry.len(); n != 0 {
+			t.Fatalf("expected empty expiry index, got %d", n)
+		}
+	})
+
+	t.Run("ExpireDue removes only due entries in batches", func(t *testing.T) {
+		clock := NewManualClock(time.Unix(1000, 0))
+		lru := NewLRU[int, int](0, 0)
+		lru.SetClock(clock)
+
+		var expired int
+		lru.OnEvict(func(_ int, _ int, reason EvictReason) {
+			if reason != EvictExpired {
+				t.Errorf("expected expired reason, got %s", reason)
+			}
+			expired++
+		})
+
+		for i := 0; i < 10; i++ {
+			lru.SetWithTTL(i, i, time.Second)
+		}
+		for i := 10; i < 15; i++ {
+			lru.SetWithTTL(i, i, time.Hour)
+		}
+		lru.Set(100, 100) // never expires
+
+		if n := lru.ExpireDue(4); n != 0 {
+			t.Fatalf("expected nothing to expire yet, got %d", n)
+		}
+
+		clock.Advance(time.Minute)
+		if n := lru.ExpireDue(4); n != 4 {
+			t.Fatalf("expected batch of 4, got %d", n)
+		}
+		if n := lru.ExpireDue(100); n != 6 {
+			t.Fatalf("expected remaining 6, got %d", n)
+		}
+		if expired != 10 {
+			t.Fatalf("expected 10 expired callbacks, got %d", expired)
+		}
+		if lru.Len() != 6 {
+			t.Fatalf("expected 6 entries left, got %d", lru.Len())
+		}
+		if s := lru.Stats(); s.Expirations != 10 {
+			t.Fatalf("expected 10 expirations in stats, got %d", s.Expirations)
+		}
+	})
+
+	t.Run("Update moves expiration", func(t *testing.T) {
+		clock := NewManualClock(time.Unix(1000, 0))
+		lru := NewLRU[string, int](0, time.Minute)
+		lru.SetClock(clock)
+
+		lru.Set("a", 1)
+		clock.Advance(50 * time.Second)
+		lru.Set("a", 2)
+		clock.Advance(50 * time.Second)
+
+		if n := lru.ExpireDue(10); n != 0 {
+			t.Fatalf("expected updated entry to stay, %d expired", n)
+		}
+
+		lru.Set("a", 3)
+		lru.SetWithTTL("a", 4, 0) // no longer expires
+		clock.Advance(time.Hour)
+		if v, ok := lru.Get("a"); !ok || v != 4 {
+			t.Fatalf("expected a = 4, got %d (%v)", v, ok)
+		}
+	})
+
+	t.Run("Evicted and deleted entries leave the index", func(t *testing.T) {
+		lru := NewLRU[int, int](2, time.Minute)
+
+		lru.Set(1, 1)
+		lru.Set(2, 2)
+		lru.Set(3, 3)
+		lru.Delete(2)
+
+		if n := lru.expiry.len(); n != 1 {
+			t.Fatalf("expected 1 key in expiry index, got %d", n)
+		}
+	})
+
+	t.Run("Janitor removes expired entries", func(t *testing.T) {
+		clock := NewManualClock(time.Unix(1000, 0))
+		lru := NewLRU[int, int](0, time.Second)
+		lru.SetClock(clock)
+		for i := 0; i < 100; i++ {
+			lru.Set(i, i)
+		}
+		clock.Advance(time.Minute)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go lru.RunJanitor(ctx, time.Millisecond, 7)
+
+		waitFor(t, func() bool { return lru.Len() == 0 })
+	})
+
+	t.Run("Janitor stops when nothing is due", func(t *testing.T) {
+		clock := NewManualClock(time.Unix(1000, 0))
+		lru := NewLRU[int, int](0, time.Second)
+		lru.SetClock(clock)
+		for i := 0; i < 10; i++ {
+			lru.Set(i, i)
+		}
+		clock.Advance(time.Minute)
+		for i := 10; i < 20; i++ {
+			lru.Set(i, i)
+		}
+
+		// batches of 5 remove all due entries with an exact last batch, then an empty one ends the loop
+		lru.expireBatches(context.Background(), 5)
+		if lru.Len() != 10 {
+			t.Fatalf("expected 10 live entries, got %d", lru.Len())
+		}
+	})
+
+	t.Run("Janitor rejects zero batch size", func(t *testing.T) {
+		clock := NewManualClock(time.Unix(1000, 0))
+		lru := NewLRU[int, int](0, time.Second)
+		lru.SetClock(clock)
+		lru.Set(1, 1)
+		clock.Advance(time.Minute)
+
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic for zero batch size")
+			}
+		}()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		lru.RunJanitor(ctx, time.Millisecond, 0)
+	})
+}
+
+func TestShardedExpiry(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	s := NewSharded[int, int](4, 0, time.Second)
+	s.SetClock(clock)
+
+	for i := 0; i < 100; i++ {
+		s.Set(i, i)
+	}
+	clock.Advance(time.Minute)
+
+	if n := s.ExpireDue(1000); n != 100 {
+		t.Fatalf("expected 100 expired, got %d", n)
+	}
+	if s.Len() != 0 {
+		t.Fatalf("expected empty cache, got %d", s.Len())
+	}
+}
+
+func TestSegmentedExpiry(t *testing.T) {
+	caches := map[string]func() Interface[string, int]{
+		"WTinyLFU": func() Interface[string, int] { return NewWTinyLFU[string, int](10, time.Minute) },
+		"ARC":      func() Interface[string, int] { return NewARC[string, int](10, time.Minute) },
+		"2Q":       func() Interface[string, int] { return NewTwoQueue[string, int](10, time.Minute) },
+	}
+
+	for name, newCache := range caches {
+		t.Run(name, func(t *testing.T) {
+			clock := NewManualClock(time.Unix(1000, 0))
+			c := newCache()
+			c.(interface{ SetClock(Clock) }).SetClock(clock)
+
+			c.Set("a", 1)
+			clock.Advance(30 * time.Second)
+			if _, ok := c.Get("a"); !ok {
+				t.Fatal("expected a to be alive before TTL")
+			}
+
+			clock.Advance(time.Minute)
+			if _, ok := c.Get("a"); ok {
+				t.Fatal("expected a to be expired")
+			}
+			if c.Len() != 0 {
+				t.Fatalf("expected expired entry to be removed on read, len %d", c.Len())
+			}
+		})
+	}
+}
+
+func TestLoadingClock(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	loads := 0
+	l := NewLoading(func(ctx context.Context, key string) (int, error) {
+		loads++
+		return loads, nil
+	}, LoadingOptions{TTL: time.Minute, MaxStale: time.Minute})
+	l.SetClock(clock)
+
+	if _, status, _ := l.Get(context.Background(), "a"); status != StatusMiss {
+		t.Fatalf("expected MISS, got %s", status)
+	}
+	clock.Advance(30 * time.Second)
+	if _, status, _ := l.Get(context.Background(), "a"); status != StatusHit {
+		t.Fatalf("expected HIT, got %s", status)
+	}
+
+	// past TTL+MaxStale the value is dropped and loaded synchronously
+	clock.Advance(3 * time.Minute)
+	if v, status, _ := l.Get(context.Background(), "a"); status != StatusMiss || v != 2 {
+		t.Fatalf("expected MISS with 2, got %s with %d", status, v)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	c := newCacheWithClock(time.Minute, clock)
+	defer c.Close()
+
+	for i := 0; i < 300; i++ {
+		c.Set(fmt.Sprintf("key-%d", i), i)
+	}
+	clock.Advance(30 * time.Second)
+	c.Set("fresh", "value")
+	clock.Advance(45 * time.Second)
+
+	if _, ok := c.Get("key-1"); ok {
+		t.Fatal("expected key-1 to expire on read")
+	}
+	if n := c.removeExpired(cleanupBatch); n != cleanupBatch {
+		t.Fatalf("expected a full batch, got %d", n)
+	}
+	if n := c.removeExpired(1000); n != 300-cleanupBatch-1 {
+		t.Fatalf("expected the rest of expired keys, got %d", n)
+	}
+	if _, ok := c.Get("fresh"); !ok {
+		t.Fatal("expected fresh key to stay")
+	}
+}
@@ -56,3 +56,24 @@ func (l *ListRounded[T]) move(e, at *node[T]) {
 	e.next.prev = e
 	e.prev.next = e
 }
+
+// pushFront inserts v right after the root, so it becomes the most recent element
+func (l *ListRounded[T]) pushFront(v T) *node[T] {
+	return l.insert(&node[T]{val: v}, &l.root)
+}
+
+// moveToFront moves e right after the root if it is not there yet
+func (l *ListRounded[T]) moveToFront(e *node[T]) {
+	if l.root.next == e {
+		return
+	}
+	l.move(e, &l.root)
+}
+
+// back returns the last (the least recent) element or nil if the list is empty
+func (l *ListRounded[T]) back() *node[T] {
+	if l.len == 0 {
+		return nil
+	}
+	return l.root.prev
+}
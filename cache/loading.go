@@ -0,0 +1,233 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Status tells how Loading.Get got the value, values match X-Cache header conventions
+type Status string
+
+const (
+	StatusHit   Status = "HIT"   // fresh value from the cache
+	StatusMiss  Status = "MISS"  // value was loaded synchronously
+	StatusStale Status = "STALE" // stale value from the cache, refresh is running in the background
+)
+
+// LoaderFunc loads the value for the key from the upstream
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+type LoadingOptions struct {
+	Capacity       int           // max number of entries, <= 0 means unbounded
+	TTL            time.Duration // values older than TTL are stale and refreshed in the background, must be positive
+	MaxStale       time.Duration // stale values older than TTL+MaxStale are dropped and loaded again, zero keeps them until evicted
+	MaxRefreshes   int           // max number of concurrent background refreshes, <= 0 means 1
+	RefreshTimeout time.Duration // timeout for a background refresh, zero means no timeout
+}
+
+type loadedValue[V any] struct {
+	val      V
+	loadedAt time.Time
+}
+
+// Loading is a read-through cache which loads missing values with the loader.
+// Concurrent misses of the same key share one loader call, stale values are served while
+// a single background refresh per key updates them.
+type Loading[K comparable, V any] struct {
+	loader LoaderFunc[K, V]
+	opts   LoadingOptions
+	clock  Clock
+	store  *LRU[K, loadedValue[V]]
+	flight *flightGroup[K, V]
+
+	refreshSem chan struct{} // bounds number of background refreshes
+
+	mu         sync.Mutex
+	refreshing map[K]struct{} // keys with a background refresh in progress
+}
+
+// NewLoading creates a loading cache, it panics if opts.TTL is not positive as every value would be stale
+func NewLoading[K comparable, V any](loader LoaderFunc[K, V], opts LoadingOptions) *Loading[K, V] {
+	if opts.TTL <= 0 {
+		panic("loading cache TTL must be positive")
+	}
+	if opts.MaxRefreshes <= 0 {
+		opts.MaxRefreshes = 1
+	}
+
+	var storeTTL time.Duration
+	if opts.MaxStale > 0 {
+		storeTTL = opts.TTL + opts.MaxStale
+	}
+
+	return &Loading[K, V]{
+		loader:     loader,
+		opts:       opts,
+		clock:      systemClock{},
+		store:      NewLRU[K, loadedValue[V]](opts.Capacity, storeTTL),
+		flight:     newFlightGroup[K, V](),
+		refreshSem: make(chan struct{}, opts.MaxRefreshes),
+		refreshing: make(map[K]struct{}),
+	}
+}
+
+// SetClock replaces the clock used for TTL and staleness, it must be called before the cache is used
+func (l *Loading[K, V]) SetClock(clock Clock) {
+	l.clock = clock
+	l.store.SetClock(clock)
+}
+
+// Get returns the cached value or loads it with the loader.
+// A stale value is returned immediately with StatusStale while the refresh runs in the background.
+func (l *Loading[K, V]) Get(ctx context.Context, key K) (V, Status, error) {
+	if cached, ok := l.store.Get(key); ok {
+		if l.clock.Now().Sub(cached.loadedAt) <= l.opts.TTL {
+			return cached.val, StatusHit, nil
+		}
+
+		l.refresh(ctx, key)
+		return cached.val, StatusStale, nil
+	}
+
+	val, err := l.load(ctx, key)
+	if err != nil {
+		var zeroVal V
+		return zeroVal, StatusMiss, err
+	}
+	return val, StatusMiss, nil
+}
+
+// Set puts the value to the cache as freshly loaded
+func (l *Loading[K, V]) Set(key K, val V) {
+	l.store.Set(key, loadedValue[V]{val: val, loadedAt: l.clock.Now()})
+}
+
+// Delete removes the value, so the next Get loads it again
+func (l *Loading[K, V]) Delete(key K) bool {
+	return l.store.Delete(key)
+}
+
+func (l *Loading[K, V]) Len() int {
+	return l.store.Len()
+}
+
+// load calls the loader once for all concurrent callers and stores the result
+func (l *Loading[K, V]) load(ctx context.Context, key K) (V, error) {
+	return l.flight.Do(key, func() (V, error) {
+		val, err := l.loader(ctx, key)
+		if err != nil {
+			return val, err
+		}
+		l.Set(key, val)
+		return val, nil
+	})
+}
+
+// refresh starts a background refresh for the key unless one is already running or the refresh limit is reached.
+// The refresh does not depend on the request context, so it is not cancelled when the request is done.
+func (l *Loading[K, V]) refresh(ctx context.Context, key K) {
+	l.mu.Lock()
+	if _, ok := l.refreshing[key]; ok {
+		l.mu.Unlock()
+		return
+	}
+
+	select {
+	case l.refreshSem <- struct{}{}:
+	default:
+		// too many refreshes, the stale value is served and the next read tries again
+		l.mu.Unlock()
+		return
+	}
+	l.refreshing[key] = struct{}{}
+	l.mu.Unlock()
+
+	go func() {
+		defer func() {
+			l.mu.Lock()
+			delete(l.refreshing, key)
+			l.mu.Unlock()
+			<-l.refreshSem
+		}()
+
+		refreshCtx := context.WithoutCancel(ctx)
+		if l.opts.RefreshTimeout > 0 {
+			var cancel context.CancelFunc
+			refreshCtx, cancel = context.WithTimeout(refreshCtx, l.opts.RefreshTimeout)
+			defer cancel()
+		}
+
+		if _, err := l.load(refreshCtx, key); err != nil {
+			// we need not lose errors and at least keep it somewhere
+			log.Printf("background refresh failed for key=%v: %v", key, err)
+		}
+	}()
+}
+
+// flightCall is an in-flight flightGroup.Do call
+type flightCall[V any] struct {
+	wg   sync.WaitGroup
+	val  V
+	err  error
+	dups int // number of callers which joined the call and wait for its result
+}
+
+// flightGroup is a generic singleflight which dedupes concurrent calls with the same key
+type flightGroup[K comparable, V any] struct {
+	mu sync.Mutex
+	m  map[K]*flightCall[V]
+}
+
+func newFlightGroup[K comparable, V any]() *flightGroup[K, V] {
+	return &flightGroup[K, V]{m: make(map[K]*flightCall[V])}
+}
+
+// Do executes fn once for all concurrent callers with the same key, panics in fn are returned as errors
+func (g *flightGroup[K, V]) Do(key K, fn func() (V, error)) (val V, err error) {
+	g.mu.Lock()
+	if existingCall, exists := g.m[key]; exists {
+		existingCall.dups++
+		g.mu.Unlock()
+		existingCall.wg.Wait()
+		return existingCall.val, existingCall.err
+	}
+
+	newCall := &flightCall[V]{}
+	newCall.wg.Add(1)
+	g.m[key] = newCall
+	g.mu.Unlock()
+
+	defer func() {
+		if v := recover(); v != nil {
+			log.Printf("panic recovered in flightGroup for key=%v: %v", key, v)
+			err = fmt.Errorf("panic: %v", v)
+			newCall.err = err
+		}
+
+		// the call is done, so the next caller starts a new one instead of reading the old result
+		g.mu.Lock()
+		delete(g.m, key)
+		g.mu.Unlock()
+		newCall.wg.Done()
+	}()
+
+	newCall.val, newCall.err = fn()
+
+	val = newCall.val
+	err = newCall.err
+	return
+}
+
+// dups returns number of callers waiting for the in-flight call with the key
+func (g *flightGroup[K, V]) dups(key K) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if c, ok := g.m[key]; ok {
+		return c.dups
+	}
+	return 0
+}
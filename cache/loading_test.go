@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLoading(t *testing.T) {
+	t.Run("Miss then hit", func(t *testing.T) {
+		var calls atomic.Int32
+		l := NewLoading(func(ctx context.Context, key string) (string, error) {
+			calls.Add(1)
+			return "value-" + key, nil
+		}, LoadingOptions{TTL: time.Minute})
+
+		v, status, err := l.Get(context.Background(), "a")
+		if err != nil || v != "value-a" || status != StatusMiss {
+			t.Fatalf("expected value-a MISS, got %q %s %v", v, status, err)
+		}
+
+		v, status, err = l.Get(context.Background(), "a")
+		if err != nil || v != "value-a" || status != StatusHit {
+			t.Fatalf("expected value-a HIT, got %q %s %v", v, status, err)
+		}
+
+		if calls.Load() != 1 {
+			t.Fatalf("expected 1 loader call, got %d", calls.Load())
+		}
+	})
+
+	t.Run("Concurrent misses share one load", func(t *testing.T) {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		l := NewLoading(func(ctx context.Context, key int) (int, error) {
+			calls.Add(1)
+			<-release
+			return key * 10, nil
+		}, LoadingOptions{TTL: time.Minute})
+
+		const parallel = 50
+		var wg sync.WaitGroup
+		results := make([]int, parallel)
+		for i := 0; i < parallel; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i], _, _ = l.Get(context.Background(), 7)
+			}(i)
+		}
+
+		// the loader is blocked until all other goroutines join its flight
+		waitFor(t, func() bool { return l.flight.dups(7) == parallel-1 })
+		close(release)
+		wg.Wait()
+
+		if calls.Load() != 1 {
+			t.Fatalf("expected 1 loader call, got %d", calls.Load())
+		}
+		for i, v := range results {
+			if v != 70 {
+				t.Fatalf("result %d: expected 70, got %d", i, v)
+			}
+		}
+	})
+
+	t.Run("Stale value is served while one refresh runs", func(t *testing.T) {
+		var calls atomic.Int32
+		release := make(chan struct{})
+		l := NewLoading(func(ctx context.Context, key string) (int32, error) {
+			n := calls.Add(1)
+			if n > 1 {
+				<-release
+			}
+			return n, nil
+		}, LoadingOptions{TTL: 50 * time.Millisecond})
+		clock := NewManualClock(time.Now())
+		l.SetClock(clock)
+
+		l.Get(context.Background(), "a")
+		clock.Advance(60 * time.Millisecond)
+
+		for i := 0; i < 10; i++ {
+			v, status, err := l.Get(context.Background(), "a")
+			if err != nil || v != 1 || status != StatusStale {
+				t.Fatalf("expected stale 1, got %d %s %v", v, status, err)
+			}
+		}
+
+		close(release)
+		waitFor(t, func() bool {
+			v, _ := l.store.Peek("a")
+			return v.val == 2
+		})
+
+		if calls.Load() != 2 {
+			t.Fatalf("expected 2 loader calls, got %d", calls.Load())
+		}
+		if v, status, _ := l.Get(context.Background(), "a"); v != 2 || status != StatusHit {
+			t.Fatalf("expected refreshed 2 HIT, got %d %s", v, status)
+		}
+	})
+
+	t.Run("Refresh concurrency is bounded", func(t *testing.T) {
+		var running, maxRunning atomic.Int32
+		release := make(chan struct{})
+		loaded := false
+		l := NewLoading(func(ctx context.Context, key int) (int, error) {
+			if !loaded {
+				return key, nil
+			}
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				m := maxRunning.Load()
+				if n <= m || maxRunning.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			<-release
+			return key, nil
+		}, LoadingOptions{TTL: time.Millisecond, MaxRefreshes: 2})
+		clock := NewManualClock(time.Now())
+		l.SetClock(clock)
+
+		for i := 0; i < 10; i++ {
+			l.Get(context.Background(), i)
+		}
+		loaded = true
+		clock.Advance(5 * time.Millisecond)
+
+		for i := 0; i < 10; i++ {
+			if _, status, _ := l.Get(context.Background(), i); status != StatusStale {
+				t.Fatalf("expected STALE, got %s", status)
+			}
+		}
+
+		waitFor(t, func() bool { return running.Load() == 2 })
+		close(release)
+		waitFor(t, func() bool { return len(l.refreshSem) == 0 })
+
+		if maxRunning.Load() != 2 {
+			t.Fatalf("expected at most 2 refreshes, got %d", maxRunning.Load())
+		}
+	})
+
+	t.Run("Errors and panics are not cached", func(t *testing.T) {
+		var calls atomic.Int32
+		errUpstream := errors.New("upstream is down")
+		l := NewLoading(func(ctx context.Context, key string) (string, error) {
+			switch calls.Add(1) {
+			case 1:
+				return "", errUpstream
+			case 2:
+				panic("loader panic")
+			default:
+				return "ok", nil
+			}
+		}, LoadingOptions{TTL: time.Minute})
+
+		if _, _, err := l.Get(context.Background(), "a"); !errors.Is(err, errUpstream) {
+			t.Fatalf("expected upstream error, got %v", err)
+		}
+		if _, _, err := l.Get(context.Background(), "a"); err == nil {
+			t.Fatal("expected panic to be returned as error")
+		}
+		if v, status, err := l.Get(context.Background(), "a"); err != nil || v != "ok" || status != StatusMiss {
+			t.Fatalf("expected ok MISS, got %q %s %v", v, status, err)
+		}
+	})
+
+	t.Run("Zero TTL is rejected", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic for zero TTL")
+			}
+		}()
+		NewLoading(func(ctx context.Context, key string) (string, error) {
+			return key, nil
+		}, LoadingOptions{})
+	})
+
+	t.Run("MaxStale drops too old values", func(t *testing.T) {
+		var calls atomic.Int32
+		l := NewLoading(func(ctx context.Context, key string) (string, error) {
+			return fmt.Sprintf("v%d", calls.Add(1)), nil
+		}, LoadingOptions{TTL: time.Millisecond, MaxStale: time.Millisecond})
+		clock := NewManualClock(time.Now())
+		l.SetClock(clock)
+
+		l.Get(context.Background(), "a")
+		clock.Advance(5 * time.Millisecond)
+
+		if v, status, _ := l.Get(context.Background(), "a"); v != "v2" || status != StatusMiss {
+			t.Fatalf("expected v2 MISS, got %q %s", v, status)
+		}
+	})
+}
+
+// waitFor polls cond until it is true or fails the test after a second
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition was not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
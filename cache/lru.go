@@ -0,0 +1,383 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTooLarge is returned when a single entry costs more than the whole cache capacity
+var ErrTooLarge = errors.New("entry cost exceeds cache capacity")
+
+// EvictReason tells the eviction callback why an entry has left the cache
+type EvictReason int
+
+const (
+	EvictCapacity EvictReason = iota // pushed out by a newer entry or by Resize
+	EvictExpired                     // entry TTL has passed
+	EvictDeleted                     // entry was removed with Delete
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case EvictCapacity:
+		return "capacity"
+	case EvictExpired:
+		return "expired"
+	case EvictDeleted:
+		return "deleted"
+	default:
+		return "unknown"
+	}
+}
+
+type lruEntry[K comparable, V any] struct {
+	key       K
+	val       V
+	cost      int
+	expiresAt time.Time      // zero time means the entry never expires
+	expiry    *expiryItem[K] // position in the expiry index, nil if the entry never expires
+}
+
+func (e *lruEntry[K, V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// LRU is a generic thread-safe least recently used cache built on ListRounded.
+// The most recently used entry is kept right after the list root, the least recently used one right before it.
+// Capacity is measured in cost units, by default every entry costs 1, so capacity is the number of entries.
+type LRU[K comparable, V any] struct {
+	mu       sync.Mutex
+	capacity int
+	cost     int // total cost of all entries
+	costFn   func(V) int
+	ttl      time.Duration
+	clock    Clock
+	items    map[K]*node[lruEntry[K, V]]
+	order    *ListRounded[lruEntry[K, V]]
+	expiry   expiryIndex[K]
+	policy   AdmissionPolicy[K] // nil admits every new key
+	onEvict  func(key K, val V, reason EvictReason)
+	counters counters
+}
+
+// NewLRU creates LRU cache which holds up to capacity entries, capacity <= 0 means the cache is unbounded.
+// ttl is the default time to live for entries added with Set, zero ttl means entries never expire.
+func NewLRU[K comparable, V any](capacity int, ttl time.Duration) *LRU[K, V] {
+	return &LRU[K, V]{
+		capacity: capacity,
+		ttl:      ttl,
+		clock:    systemClock{},
+		items:    make(map[K]*node[lruEntry[K, V]]),
+		order:    NewListRounded[lruEntry[K, V]](capacity),
+	}
+}
+
+// NewWeightedLRU creates LRU cache which holds entries until their total cost reaches capacity,
+// for example capacity can be a memory budget in bytes. nil costFn means DefaultCost.
+func NewWeightedLRU[K comparable, V any](capacity int, ttl time.Duration, costFn func(V) int) *LRU[K, V] {
+	if costFn == nil {
+		costFn = DefaultCost[V]
+	}
+
+	c := NewLRU[K, V](capacity, ttl)
+	c.costFn = costFn
+	return c
+}
+
+// DefaultCost returns byte length of []byte and string values and 1 for other types
+func DefaultCost[V any](v V) int {
+	switch val := any(v).(type) {
+	case []byte:
+		return max(len(val), 1)
+	case string:
+		return max(len(val), 1)
+	default:
+		return 1
+	}
+}
+
+// OnEvict registers a callback which is called for every entry removed from the cache.
+// The callback is called after the cache lock is released, so it is safe to use the cache inside it.
+func (c *LRU[K, V]) OnEvict(fn func(key K, val V, reason EvictReason)) {
+	c.mu.Lock()
+	c.onEvict = fn
+	c.mu.Unlock()
+}
+
+// SetAdmission makes the cache ask the policy before a new key evicts the least recently used entry,
+// e.g. NewTinyLFU keeps popular entries when the cache is full of one-off keys. nil policy admits every key.
+func (c *LRU[K, V]) SetAdmission(policy AdmissionPolicy[K]) {
+	c.mu.Lock()
+	c.policy = policy
+	c.mu.Unlock()
+}
+
+// SetClock replaces the clock used for TTL, tests use ManualClock to move time without sleeping
+func (c *LRU[K, V]) SetClock(clock Clock) {
+	c.mu.Lock()
+	c.clock = clock
+	c.mu.Unlock()
+}
+
+// Set adds or updates the value using the default cache TTL.
+// A value which costs more than the whole capacity is not stored and the old value of the key is deleted,
+// so Get never returns a value which was replaced.
+// With an admission policy a new key may be rejected when the cache is full, then the value is not stored either.
+func (c *LRU[K, V]) Set(k K, v V) {
+	c.set(k, v, c.ttl)
+}
+
+// SetWithTTL adds or updates the value with its own TTL, zero ttl means the entry never expires
+func (c *LRU[K, V]) SetWithTTL(k K, v V, ttl time.Duration) {
+	c.set(k, v, ttl)
+}
+
+// TrySet adds or updates the value using the default cache TTL.
+// It returns ErrTooLarge if the value costs more than the whole capacity, then the old value of the key is deleted.
+func (c *LRU[K, V]) TrySet(k K, v V) error {
+	return c.set(k, v, c.ttl)
+}
+
+func (c *LRU[K, V]) set(k K, v V, ttl time.Duration) error {
+	cost := 1
+	if c.costFn != nil {
+		cost = c.costFn(v)
+	}
+
+	c.mu.Lock()
+	if c.policy != nil {
+		c.policy.Record(k)
+	}
+
+	if c.capacity > 0 && cost > c.capacity {
+		// the caller replaced the value, keeping the old one would make the write look lost
+		var deleted []lruEntry[K, V]
+		if elem, ok := c.items[k]; ok {
+			deleted = append(deleted, c.removeElement(elem))
+		}
+		onEvict := c.onEvict
+		c.mu.Unlock()
+
+		c.notify(onEvict, deleted, EvictDeleted)
+		return ErrTooLarge
+	}
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = c.clock.Now().Add(ttl)
+	}
+
+	elem, exists := c.items[k]
+	if !exists && !c.admitted(k, cost) {
+		c.mu.Unlock()
+		return nil
+	}
+
+	if exists {
+		c.cost += cost - elem.val.cost
+		elem.val.val = v
+		elem.val.cost = cost
+		elem.val.expiresAt = expiresAt
+		c.order.moveToFront(elem)
+	} else {
+		c.cost += cost
+		elem = c.order.pushFront(lruEntry[K, V]{key: k, val: v, cost: cost, expiresAt: expiresAt})
+		c.items[k] = elem
+	}
+	elem.val.expiry = c.expiry.set(elem.val.expiry, k, expiresAt)
+
+	// the new entry is at the front, so it is never evicted here as it fits into capacity alone
+	var evicted []lruEntry[K, V]
+	for c.capacity > 0 && c.cost > c.capacity {
+		evicted = append(evicted, c.removeElement(c.order.back()))
+	}
+	onEvict := c.onEvict
+	c.mu.Unlock()
+
+	c.notify(onEvict, evicted, EvictCapacity)
+	return nil
+}
+
+// Get returns the value and marks it as the most recently used one.
+// Expired entries are removed on read.
+func (c *LRU[K, V]) Get(k K) (V, bool) {
+	c.mu.Lock()
+	if c.policy != nil {
+		c.policy.Record(k)
+	}
+
+	elem, ok := c.items[k]
+	if !ok {
+		c.mu.Unlock()
+		c.counters.misses.Add(1)
+		var zeroVal V
+		return zeroVal, false
+	}
+
+	if elem.val.expired(c.clock.Now()) {
+		c.counters.misses.Add(1)
+		ent := c.removeElement(elem)
+		onEvict := c.onEvict
+		c.mu.Unlock()
+
+		c.notify(onEvict, []lruEntry[K, V]{ent}, EvictExpired)
+		var zeroVal V
+		return zeroVal, false
+	}
+
+	c.order.moveToFront(elem)
+	v := elem.val.val
+	c.mu.Unlock()
+	c.counters.hits.Add(1)
+	return v, true
+}
+
+// Peek returns the value without updating its recency
+func (c *LRU[K, V]) Peek(k K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[k]
+	if !ok || elem.val.expired(c.clock.Now()) {
+		var zeroVal V
+		return zeroVal, false
+	}
+	return elem.val.val, true
+}
+
+// Delete removes the value and reports whether it was present
+func (c *LRU[K, V]) Delete(k K) bool {
+	c.mu.Lock()
+	elem, ok := c.items[k]
+	if !ok {
+		c.mu.Unlock()
+		return false
+	}
+
+	ent := c.removeElement(elem)
+	onEvict := c.onEvict
+	c.mu.Unlock()
+
+	c.notify(onEvict, []lruEntry[K, V]{ent}, EvictDeleted)
+	return true
+}
+
+// Len returns number of entries in the cache including expired ones which were not read yet
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.len
+}
+
+// Cost returns total cost of all entries, it equals Len unless the cache is weighted
+func (c *LRU[K, V]) Cost() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cost
+}
+
+// Resize changes the cache capacity and evicts the least recently used entries which do not fit anymore.
+// It returns number of evicted entries.
+func (c *LRU[K, V]) Resize(capacity int) int {
+	c.mu.Lock()
+	c.capacity = capacity
+	c.order.capacity = capacity
+
+	var evicted []lruEntry[K, V]
+	for capacity > 0 && c.cost > capacity {
+		evicted = append(evicted, c.removeElement(c.order.back()))
+	}
+	onEvict := c.onEvict
+	c.mu.Unlock()
+
+	c.notify(onEvict, evicted, EvictCapacity)
+	return len(evicted)
+}
+
+// admitted asks the admission policy about every victim which a new key of the cost would evict,
+// the key is rejected if it loses to any of them. Must be called with c.mu held.
+func (c *LRU[K, V]) admitted(k K, cost int) bool {
+	if c.policy == nil || c.capacity <= 0 {
+		return true
+	}
+
+	need := c.cost + cost - c.capacity
+	for victim := c.order.back(); need > 0 && victim != &c.order.root; victim = victim.prev {
+		if !c.policy.Admit(k, victim.val.key) {
+			return false
+		}
+		need -= victim.val.cost
+	}
+	return true
+}
+
+// ExpireDue removes up to limit expired entries and returns number of removed ones.
+// Only due entries are visited, so the lock is held for a short time even in a big cache.
+func (c *LRU[K, V]) ExpireDue(limit int) int {
+	c.mu.Lock()
+	keys := c.expiry.popDue(c.clock.Now(), limit)
+
+	evicted := make([]lruEntry[K, V], 0, len(keys))
+	for _, k := range keys {
+		evicted = append(evicted, c.removeElement(c.items[k]))
+	}
+	onEvict := c.onEvict
+	c.mu.Unlock()
+
+	c.notify(onEvict, evicted, EvictExpired)
+	return len(evicted)
+}
+
+// RunJanitor removes expired entries every interval in batches of batchSize until ctx is done, batchSize must be positive.
+// The lock is released between batches, so readers are not blocked by a long cleanup. It blocks, so run it in a goroutine.
+func (c *LRU[K, V]) RunJanitor(ctx context.Context, interval time.Duration, batchSize int) {
+	if batchSize <= 0 {
+		panic("janitor batch size must be positive")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.expireBatches(ctx, batchSize)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// expireBatches calls ExpireDue until a batch comes out short, so there are no due entries left
+func (c *LRU[K, V]) expireBatches(ctx context.Context, batchSize int) {
+	for ctx.Err() == nil {
+		if c.ExpireDue(batchSize) < batchSize {
+			return
+		}
+	}
+}
+
+// removeElement removes elem from the map, the list and the expiry index, must be called with c.mu held
+func (c *LRU[K, V]) removeElement(elem *node[lruEntry[K, V]]) lruEntry[K, V] {
+	ent := elem.val
+	delete(c.items, ent.key)
+	c.order.remove(elem)
+	c.expiry.remove(ent.expiry)
+	c.cost -= ent.cost
+	return ent
+}
+
+// notify counts removed entries and calls the eviction callback, must be called without c.mu held
+func (c *LRU[K, V]) notify(onEvict func(K, V, EvictReason), evicted []lruEntry[K, V], reason EvictReason) {
+	c.counters.count(reason, len(evicted))
+	if onEvict == nil {
+		return
+	}
+	for _, ent := range evicted {
+		onEvict(ent.key, ent.val, reason)
+	}
+}
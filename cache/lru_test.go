@@ -0,0 +1,184 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLRU(t *testing.T) {
+	t.Run("Evicts least recently used", func(t *testing.T) {
+		lru := NewLRU[string, int](3, 0)
+
+		lru.Set("a", 1)
+		lru.Set("b", 2)
+		lru.Set("c", 3)
+
+		lru.Get("a") // Use a, so b becomes the least recently used
+
+		lru.Set("d", 4)
+
+		if _, ok := lru.Get("b"); ok {
+			t.Fatal("expected b to be evicted")
+		}
+		for _, k := range []string{"a", "c", "d"} {
+			if _, ok := lru.Get(k); !ok {
+				t.Fatalf("expected %s to be in cache", k)
+			}
+		}
+		if lru.Len() != 3 {
+			t.Fatalf("expected len 3, got %d", lru.Len())
+		}
+	})
+
+	t.Run("Set updates existing value", func(t *testing.T) {
+		lru := NewLRU[string, int](2, 0)
+
+		lru.Set("a", 1)
+		lru.Set("b", 2)
+		lru.Set("a", 10) // a becomes the most recent
+		lru.Set("c", 3)
+
+		if v, ok := lru.Get("a"); !ok || v != 10 {
+			t.Fatalf("expected a = 10, got %d (%v)", v, ok)
+		}
+		if _, ok := lru.Get("b"); ok {
+			t.Fatal("expected b to be evicted")
+		}
+	})
+
+	t.Run("Peek does not change order", func(t *testing.T) {
+		lru := NewLRU[int, string](2, 0)
+
+		lru.Set(1, "one")
+		lru.Set(2, "two")
+
+		if v, ok := lru.Peek(1); !ok || v != "one" {
+			t.Fatalf("expected one, got %q", v)
+		}
+
+		lru.Set(3, "three")
+
+		if _, ok := lru.Peek(1); ok {
+			t.Fatal("expected 1 to be evicted as Peek must not promote it")
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		lru := NewLRU[string, int](2, 0)
+
+		lru.Set("a", 1)
+		if !lru.Delete("a") {
+			t.Fatal("expected Delete to report existing key")
+		}
+		if lru.Delete("a") {
+			t.Fatal("expected Delete to report missing key")
+		}
+		if lru.Len() != 0 {
+			t.Fatalf("expected empty cache, got %d", lru.Len())
+		}
+	})
+
+	t.Run("Resize evicts oldest", func(t *testing.T) {
+		lru := NewLRU[int, int](5, 0)
+		for i := 0; i < 5; i++ {
+			lru.Set(i, i)
+		}
+
+		if n := lru.Resize(2); n != 3 {
+			t.Fatalf("expected 3 evictions, got %d", n)
+		}
+		for i := 0; i < 3; i++ {
+			if _, ok := lru.Peek(i); ok {
+				t.Fatalf("expected %d to be evicted", i)
+			}
+		}
+
+		lru.Resize(4)
+		lru.Set(5, 5)
+		lru.Set(6, 6)
+		if lru.Len() != 4 {
+			t.Fatalf("expected len 4, got %d", lru.Len())
+		}
+	})
+
+	t.Run("Per-entry TTL", func(t *testing.T) {
+		lru := NewLRU[string, int](10, time.Hour)
+		clock := NewManualClock(time.Now())
+		lru.SetClock(clock)
+
+		lru.SetWithTTL("short", 1, 10*time.Millisecond)
+		lru.Set("long", 2)
+		lru.SetWithTTL("forever", 3, 0)
+
+		clock.Advance(20 * time.Millisecond)
+
+		if _, ok := lru.Peek("short"); ok {
+			t.Fatal("expected short to be expired")
+		}
+		if _, ok := lru.Get("short"); ok {
+			t.Fatal("expected short to be expired")
+		}
+		if _, ok := lru.Get("long"); !ok {
+			t.Fatal("expected long to be alive")
+		}
+		if _, ok := lru.Get("forever"); !ok {
+			t.Fatal("expected forever to be alive")
+		}
+		if lru.Len() != 2 {
+			t.Fatalf("expected expired entry to be removed on read, len = %d", lru.Len())
+		}
+	})
+
+	t.Run("Eviction callback", func(t *testing.T) {
+		lru := NewLRU[string, int](1, 0)
+
+		reasons := map[string]EvictReason{}
+		lru.OnEvict(func(k string, v int, reason EvictReason) {
+			reasons[k] = reason
+			lru.Len() // callback is called without the lock held
+		})
+
+		lru.Set("a", 1)
+		lru.Set("b", 2)
+		lru.Delete("b")
+		clock := NewManualClock(time.Now())
+		lru.SetClock(clock)
+		lru.SetWithTTL("c", 3, time.Nanosecond)
+		clock.Advance(time.Millisecond)
+		lru.Get("c")
+
+		expected := map[string]EvictReason{"a": EvictCapacity, "b": EvictDeleted, "c": EvictExpired}
+		for k, reason := range expected {
+			if reasons[k] != reason {
+				t.Errorf("expected %s to be evicted with %s, got %s", k, reason, reasons[k])
+			}
+		}
+	})
+
+	t.Run("Concurrent access", func(t *testing.T) {
+		lru := NewLRU[string, int](100, time.Minute)
+
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					key := fmt.Sprintf("key-%d", i%150)
+					lru.Set(key, i)
+					lru.Get(key)
+					if i%10 == g {
+						lru.Delete(key)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+
+		if lru.Len() > 100 {
+			t.Fatalf("cache exceeded capacity: %d", lru.Len())
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWeightedLRU(t *testing.T) {
+	t.Run("Default cost is byte length", func(t *testing.T) {
+		if got := DefaultCost([]byte("hello")); got != 5 {
+			t.Fatalf("expected 5, got %d", got)
+		}
+		if got := DefaultCost("hi"); got != 2 {
+			t.Fatalf("expected 2, got %d", got)
+		}
+		if got := DefaultCost(struct{ A, B int }{1, 2}); got != 1 {
+			t.Fatalf("expected 1, got %d", got)
+		}
+	})
+
+	t.Run("Evicts until total cost fits", func(t *testing.T) {
+		c := NewWeightedLRU[string, []byte](100, 0, nil)
+
+		c.Set("a", bytes.Repeat([]byte("a"), 40))
+		c.Set("b", bytes.Repeat([]byte("b"), 40))
+		c.Set("c", bytes.Repeat([]byte("c"), 10))
+		if c.Cost() != 90 || c.Len() != 3 {
+			t.Fatalf("expected cost 90 with 3 entries, got %d with %d", c.Cost(), c.Len())
+		}
+
+		// 60 more bytes push out both a and b
+		c.Get("c")
+		c.Set("d", bytes.Repeat([]byte("d"), 60))
+		if c.Cost() != 70 || c.Len() != 2 {
+			t.Fatalf("expected cost 70 with 2 entries, got %d with %d", c.Cost(), c.Len())
+		}
+		for _, k := range []string{"a", "b"} {
+			if _, ok := c.Peek(k); ok {
+				t.Fatalf("expected %s to be evicted", k)
+			}
+		}
+	})
+
+	t.Run("Update changes cost", func(t *testing.T) {
+		c := NewWeightedLRU[string, string](10, 0, nil)
+
+		c.Set("a", "aaaa")
+		c.Set("b", "bbbb")
+		c.Set("a", "aaaaaaaa") // 8 + 4 does not fit, b goes
+
+		if c.Cost() != 8 {
+			t.Fatalf("expected cost 8, got %d", c.Cost())
+		}
+		if _, ok := c.Peek("b"); ok {
+			t.Fatal("expected b to be evicted")
+		}
+	})
+
+	t.Run("Rejects entries larger than capacity", func(t *testing.T) {
+		c := NewWeightedLRU[string, []byte](10, 0, nil)
+
+		c.Set("small", []byte("1234"))
+		if err := c.TrySet("huge", make([]byte, 11)); !errors.Is(err, ErrTooLarge) {
+			t.Fatalf("expected ErrTooLarge, got %v", err)
+		}
+		if c.Cost() != 4 {
+			t.Fatalf("expected rejected entry to leave cost 4, got %d", c.Cost())
+		}
+
+		var reasons []EvictReason
+		c.OnEvict(func(key string, val []byte, reason EvictReason) {
+			reasons = append(reasons, reason)
+		})
+		c.Set("small", make([]byte, 11))
+		if v, ok := c.Peek("small"); ok {
+			t.Fatalf("expected rejected update to delete the old value, got %q", v)
+		}
+		if c.Cost() != 0 || c.Len() != 0 {
+			t.Fatalf("expected empty cache, got cost %d and %d entries", c.Cost(), c.Len())
+		}
+		if len(reasons) != 1 || reasons[0] != EvictDeleted {
+			t.Fatalf("expected the old value to be reported as deleted, got %v", reasons)
+		}
+	})
+
+	t.Run("Admission is asked about every victim", func(t *testing.T) {
+		c := NewWeightedLRU[string, string](10, 0, nil)
+		c.SetAdmission(rejectVictims[string]{"b": true})
+
+		c.Set("a", "aaaa")
+		c.Set("b", "bbbb")
+
+		// 7 bytes would evict both a and b, b is protected, so nothing changes
+		c.Set("c", "ccccccc")
+		if _, ok := c.Peek("c"); ok || c.Cost() != 8 {
+			t.Fatalf("expected c to be rejected with cost 8 kept, got cost %d", c.Cost())
+		}
+
+		// 3 bytes evict only a
+		c.Set("d", "ddd")
+		if _, ok := c.Peek("a"); ok || c.Cost() != 7 {
+			t.Fatalf("expected d to replace a, got cost %d", c.Cost())
+		}
+	})
+
+	t.Run("Custom cost and resize", func(t *testing.T) {
+		c := NewWeightedLRU[int, int](100, 0, func(v int) int { return v })
+
+		for i := 1; i <= 10; i++ {
+			c.Set(i, 10)
+		}
+		if c.Len() != 10 {
+			t.Fatalf("expected 10 entries, got %d", c.Len())
+		}
+
+		if n := c.Resize(35); n != 7 {
+			t.Fatalf("expected 7 evictions, got %d", n)
+		}
+		if c.Cost() != 30 {
+			t.Fatalf("expected cost 30, got %d", c.Cost())
+		}
+	})
+}
+
+// rejectVictims is AdmissionPolicy which never lets a new key evict the listed keys
+type rejectVictims[K comparable] map[K]bool
+
+func (p rejectVictims[K]) Record(k K) {}
+
+func (p rejectVictims[K]) Admit(candidate, victim K) bool {
+	return !p[victim]
+}
@@ -0,0 +1,232 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Interface is the common interface of generic caches with different replacement policies
+type Interface[K comparable, V any] interface {
+	Get(k K) (V, bool)
+	Set(k K, v V)
+	Peek(k K) (V, bool)
+	Delete(k K) bool
+	Len() int
+}
+
+var (
+	_ Interface[string, int] = (*LRU[string, int])(nil)
+	_ Interface[string, int] = (*Sharded[string, int])(nil)
+	_ Interface[string, int] = (*WTinyLFU[string, int])(nil)
+	_ Interface[string, int] = (*ARC[string, int])(nil)
+	_ Interface[string, int] = (*TwoQueue[string, int])(nil)
+)
+
+// segment is an index of the list which holds the entry in a segmented cache
+type segment uint8
+
+type segmentEntry[K comparable, V any] struct {
+	key       K
+	val       V
+	hash      uint64    // key hash, used only by policies which need it
+	expiresAt time.Time // zero time means the entry never expires
+	segment   segment
+}
+
+func (e *segmentEntry[K, V]) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// segmented keeps resident entries in several ListRounded segments and implements methods shared by
+// W-TinyLFU, ARC and 2Q caches. Policies decide where entries go, segmented only moves them around.
+type segmented[K comparable, V any] struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	clock    Clock
+	items    map[K]*node[segmentEntry[K, V]]
+	segments []*ListRounded[segmentEntry[K, V]]
+	onEvict  func(key K, val V, reason EvictReason)
+	counters counters
+}
+
+func newSegmented[K comparable, V any](ttl time.Duration, capacities ...int) segmented[K, V] {
+	segments := make([]*ListRounded[segmentEntry[K, V]], len(capacities))
+	for i, capacity := range capacities {
+		segments[i] = NewListRounded[segmentEntry[K, V]](capacity)
+	}
+
+	return segmented[K, V]{
+		ttl:      ttl,
+		clock:    systemClock{},
+		items:    make(map[K]*node[segmentEntry[K, V]]),
+		segments: segments,
+	}
+}
+
+// OnEvict registers a callback which is called for every entry removed from the cache.
+// The callback is called after the cache lock is released, so it is safe to use the cache inside it.
+func (s *segmented[K, V]) OnEvict(fn func(key K, val V, reason EvictReason)) {
+	s.mu.Lock()
+	s.onEvict = fn
+	s.mu.Unlock()
+}
+
+// SetClock replaces the clock used for TTL
+func (s *segmented[K, V]) SetClock(clock Clock) {
+	s.mu.Lock()
+	s.clock = clock
+	s.mu.Unlock()
+}
+
+// Peek returns the value without updating the policy state
+func (s *segmented[K, V]) Peek(k K) (V, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, ok := s.items[k]
+	if !ok || elem.val.expired(s.clock.Now()) {
+		var zeroVal V
+		return zeroVal, false
+	}
+	return elem.val.val, true
+}
+
+// Delete removes the value and reports whether it was present
+func (s *segmented[K, V]) Delete(k K) bool {
+	s.mu.Lock()
+	elem, ok := s.items[k]
+	if !ok {
+		s.mu.Unlock()
+		return false
+	}
+
+	s.unlock([]segmentEntry[K, V]{s.removeElement(elem)}, EvictDeleted)
+	return true
+}
+
+// Len returns number of resident entries
+func (s *segmented[K, V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.items)
+}
+
+// expiresAt returns expiration time for a new entry according to the default TTL, must be called with s.mu held
+func (s *segmented[K, V]) expiresAt() time.Time {
+	if s.ttl <= 0 {
+		return time.Time{}
+	}
+	return s.clock.Now().Add(s.ttl)
+}
+
+// lookup returns resident entry for the key, must be called with s.mu held.
+// An expired entry is removed and returned as the second result.
+func (s *segmented[K, V]) lookup(k K) (*node[segmentEntry[K, V]], []segmentEntry[K, V]) {
+	elem, ok := s.items[k]
+	if !ok {
+		return nil, nil
+	}
+	if elem.val.expired(s.clock.Now()) {
+		return nil, []segmentEntry[K, V]{s.removeElement(elem)}
+	}
+	return elem, nil
+}
+
+// countGet registers result of Get
+func (s *segmented[K, V]) countGet(hit bool) {
+	if hit {
+		s.counters.hits.Add(1)
+	} else {
+		s.counters.misses.Add(1)
+	}
+}
+
+// push adds a new entry to the front of the target segment, must be called with s.mu held
+func (s *segmented[K, V]) push(target segment, ent segmentEntry[K, V]) *node[segmentEntry[K, V]] {
+	ent.segment = target
+	elem := s.segments[target].pushFront(ent)
+	s.items[ent.key] = elem
+	return elem
+}
+
+// relink moves elem to the front of the target segment, must be called with s.mu held
+func (s *segmented[K, V]) relink(elem *node[segmentEntry[K, V]], target segment) {
+	s.segments[elem.val.segment].remove(elem)
+	elem.val.segment = target
+	s.segments[target].insert(elem, &s.segments[target].root)
+}
+
+// removeElement removes elem from both the map and its segment, must be called with s.mu held
+func (s *segmented[K, V]) removeElement(elem *node[segmentEntry[K, V]]) segmentEntry[K, V] {
+	ent := elem.val
+	delete(s.items, ent.key)
+	s.segments[ent.segment].remove(elem)
+	return ent
+}
+
+// unlock releases s.mu, counts evicted entries and then calls the eviction callback for them
+func (s *segmented[K, V]) unlock(evicted []segmentEntry[K, V], reason EvictReason) {
+	onEvict := s.onEvict
+	s.mu.Unlock()
+
+	s.counters.count(reason, len(evicted))
+	if onEvict == nil {
+		return
+	}
+	for _, ent := range evicted {
+		onEvict(ent.key, ent.val, reason)
+	}
+}
+
+// ghostList remembers keys of recently evicted entries without their values.
+// ARC and 2Q use ghost hits to find out that an entry was evicted too early.
+type ghostList[K comparable] struct {
+	capacity int
+	keys     map[K]*node[K]
+	order    *ListRounded[K]
+}
+
+func newGhostList[K comparable](capacity int) *ghostList[K] {
+	return &ghostList[K]{
+		capacity: capacity,
+		keys:     make(map[K]*node[K]),
+		order:    NewListRounded[K](capacity),
+	}
+}
+
+// add puts the key to the front and forgets the oldest keys which do not fit
+func (g *ghostList[K]) add(k K) {
+	if elem, ok := g.keys[k]; ok {
+		g.order.moveToFront(elem)
+		return
+	}
+
+	g.keys[k] = g.order.pushFront(k)
+	for g.order.len > g.capacity {
+		g.removeOldest()
+	}
+}
+
+func (g *ghostList[K]) contains(k K) bool {
+	_, ok := g.keys[k]
+	return ok
+}
+
+func (g *ghostList[K]) remove(k K) {
+	if elem, ok := g.keys[k]; ok {
+		delete(g.keys, k)
+		g.order.remove(elem)
+	}
+}
+
+func (g *ghostList[K]) removeOldest() {
+	if elem := g.order.back(); elem != nil {
+		delete(g.keys, elem.val)
+		g.order.remove(elem)
+	}
+}
+
+func (g *ghostList[K]) len() int {
+	return g.order.len
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"hash/maphash"
+	"time"
+)
+
+// Sharded is a concurrent LRU cache which spreads keys over independently locked LRU shards.
+// Every key is hashed to exactly one shard, so Get and Set on different shards never wait for each other.
+// LRU order and capacity are tracked per shard, which makes the eviction approximate for the cache as a whole.
+type Sharded[K comparable, V any] struct {
+	seed   maphash.Seed
+	mask   uint64
+	shards []*LRU[K, V]
+}
+
+// NewSharded creates sharded cache with shardCount shards rounded up to a power of two.
+// capacity is the total capacity split evenly between shards, capacity <= 0 means shards are unbounded.
+// ttl is the default time to live for entries, zero ttl means entries never expire.
+func NewSharded[K comparable, V any](shardCount int, capacity int, ttl time.Duration) *Sharded[K, V] {
+	n := 1
+	for n < shardCount {
+		n <<= 1
+	}
+
+	shards := make([]*LRU[K, V], n)
+	for i := range shards {
+		shards[i] = NewLRU[K, V](shardCapacity(capacity, n), ttl)
+	}
+
+	return &Sharded[K, V]{
+		seed:   maphash.MakeSeed(),
+		mask:   uint64(n - 1),
+		shards: shards,
+	}
+}
+
+// shardCapacity splits total capacity between n shards rounding up, so the whole capacity is always available
+func shardCapacity(capacity, n int) int {
+	if capacity <= 0 {
+		return 0
+	}
+	return (capacity + n - 1) / n
+}
+
+// shard returns the shard responsible for the key
+func (s *Sharded[K, V]) shard(k K) *LRU[K, V] {
+	return s.shards[maphash.Comparable(s.seed, k)&s.mask]
+}
+
+// OnEvict registers the eviction callback on every shard
+func (s *Sharded[K, V]) OnEvict(fn func(key K, val V, reason EvictReason)) {
+	for _, shard := range s.shards {
+		shard.OnEvict(fn)
+	}
+}
+
+// SetClock replaces the clock used for TTL on every shard
+func (s *Sharded[K, V]) SetClock(clock Clock) {
+	for _, shard := range s.shards {
+		shard.SetClock(clock)
+	}
+}
+
+// ExpireDue removes up to limit expired entries from every shard and returns number of removed ones
+func (s *Sharded[K, V]) ExpireDue(limit int) int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.ExpireDue(limit)
+	}
+	return n
+}
+
+func (s *Sharded[K, V]) Set(k K, v V) {
+	s.shard(k).Set(k, v)
+}
+
+func (s *Sharded[K, V]) SetWithTTL(k K, v V, ttl time.Duration) {
+	s.shard(k).SetWithTTL(k, v, ttl)
+}
+
+func (s *Sharded[K, V]) Get(k K) (V, bool) {
+	return s.shard(k).Get(k)
+}
+
+func (s *Sharded[K, V]) Peek(k K) (V, bool) {
+	return s.shard(k).Peek(k)
+}
+
+func (s *Sharded[K, V]) Delete(k K) bool {
+	return s.shard(k).Delete(k)
+}
+
+// Len sums lengths of all shards, shards are locked one by one so the result is not an atomic snapshot
+func (s *Sharded[K, V]) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Resize splits the new total capacity between shards and returns number of evicted entries
+func (s *Sharded[K, V]) Resize(capacity int) int {
+	evicted := 0
+	for _, shard := range s.shards {
+		evicted += shard.Resize(shardCapacity(capacity, len(s.shards)))
+	}
+	return evicted
+}
+
+// ShardCount returns number of shards
+func (s *Sharded[K, V]) ShardCount() int {
+	return len(s.shards)
+}
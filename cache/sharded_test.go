@@ -0,0 +1,205 @@
+package cache
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSharded(t *testing.T) {
+	t.Run("Shard count is rounded to power of two", func(t *testing.T) {
+		s := NewSharded[string, int](5, 100, 0)
+		if s.ShardCount() != 8 {
+			t.Fatalf("expected 8 shards, got %d", s.ShardCount())
+		}
+	})
+
+	t.Run("Set Get Delete", func(t *testing.T) {
+		s := NewSharded[string, int](4, 1000, time.Minute)
+
+		for i := 0; i < 100; i++ {
+			s.Set(fmt.Sprintf("key-%d", i), i)
+		}
+		for i := 0; i < 100; i++ {
+			if v, ok := s.Get(fmt.Sprintf("key-%d", i)); !ok || v != i {
+				t.Fatalf("expected key-%d = %d, got %d (%v)", i, i, v, ok)
+			}
+		}
+
+		if !s.Delete("key-1") {
+			t.Fatal("expected key-1 to be deleted")
+		}
+		if _, ok := s.Peek("key-1"); ok {
+			t.Fatal("expected key-1 to be missing")
+		}
+		if s.Len() != 99 {
+			t.Fatalf("expected len 99, got %d", s.Len())
+		}
+	})
+
+	t.Run("Keys are spread over shards", func(t *testing.T) {
+		s := NewSharded[int, int](8, 0, 0)
+		for i := 0; i < 8000; i++ {
+			s.Set(i, i)
+		}
+
+		for i, shard := range s.shards {
+			// uniform distribution gives 1000 keys per shard
+			if n := shard.Len(); n < 800 || n > 1200 {
+				t.Errorf("shard %d has unbalanced number of keys: %d", i, n)
+			}
+		}
+	})
+
+	t.Run("Capacity and TTL semantics", func(t *testing.T) {
+		s := NewSharded[int, int](4, 40, 0)
+
+		var evicted atomic.Int64
+		s.OnEvict(func(k int, v int, reason EvictReason) {
+			if reason == EvictCapacity {
+				evicted.Add(1)
+			}
+		})
+
+		for i := 0; i < 1000; i++ {
+			s.Set(i, i)
+		}
+		if s.Len() > 40 {
+			t.Fatalf("cache exceeded capacity: %d", s.Len())
+		}
+		if int(evicted.Load()) != 1000-s.Len() {
+			t.Fatalf("expected %d evictions, got %d", 1000-s.Len(), evicted.Load())
+		}
+
+		clock := NewManualClock(time.Now())
+		s.SetClock(clock)
+		s.SetWithTTL(-1, -1, time.Millisecond)
+		clock.Advance(5 * time.Millisecond)
+		if _, ok := s.Get(-1); ok {
+			t.Fatal("expected entry to be expired")
+		}
+
+		s.Resize(8)
+		if s.Len() > 8 {
+			t.Fatalf("cache exceeded capacity after resize: %d", s.Len())
+		}
+	})
+
+	t.Run("Concurrent access", func(t *testing.T) {
+		s := NewSharded[string, int](16, 500, time.Minute)
+
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 2000; i++ {
+					key := fmt.Sprintf("key-%d", i%700)
+					s.Set(key, i)
+					s.Get(key)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if s.Len() > 500+16 {
+			t.Fatalf("cache exceeded capacity: %d", s.Len())
+		}
+	})
+}
+
+// mixedCache is the common subset of cache methods used by the benchmarks
+type mixedCache interface {
+	Set(k string, v int)
+	Get(k string) (int, bool)
+}
+
+// Adapters for interface{} based caches from cache_test.go and cache_lru_test.go
+type cacheAdapter struct{ c *Cache }
+
+func (a cacheAdapter) Set(k string, v int) { a.c.Set(k, v) }
+func (a cacheAdapter) Get(k string) (int, bool) {
+	v, ok := a.c.Get(k)
+	if !ok {
+		return 0, false
+	}
+	return v.(int), true
+}
+
+type lruCacheAdapter struct{ c *LRUCache }
+
+func (a lruCacheAdapter) Set(k string, v int) { a.c.Set(k, v) }
+func (a lruCacheAdapter) Get(k string) (int, bool) {
+	v, ok := a.c.Get(k)
+	if !ok {
+		return 0, false
+	}
+	return v.(int), true
+}
+
+const benchKeys = 1 << 14
+
+// BenchmarkParallelCaches compares single mutex caches with the sharded one under 90% reads, 10% writes
+// go test -bench=BenchmarkParallelCaches -run=^$ ./cache/
+func BenchmarkParallelCaches(b *testing.B) {
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+	}
+
+	caches := []struct {
+		name string
+		new  func() (mixedCache, func())
+	}{
+		{"Cache", func() (mixedCache, func()) {
+			c := NewCache(time.Minute)
+			return cacheAdapter{c}, c.Close
+		}},
+		{"LRUCache", func() (mixedCache, func()) {
+			c := NewLRUCache(benchKeys/2, time.Minute)
+			return lruCacheAdapter{c}, c.Close
+		}},
+		{"LRU", func() (mixedCache, func()) {
+			return NewLRU[string, int](benchKeys/2, time.Minute), func() {}
+		}},
+		{"Sharded-16", func() (mixedCache, func()) {
+			return NewSharded[string, int](16, benchKeys/2, time.Minute), func() {}
+		}},
+		{"Sharded-64", func() (mixedCache, func()) {
+			return NewSharded[string, int](64, benchKeys/2, time.Minute), func() {}
+		}},
+	}
+
+	for _, procs := range []int{1, 4, 8, 16} {
+		for _, cc := range caches {
+			b.Run(fmt.Sprintf("%s/procs-%d", cc.name, procs), func(b *testing.B) {
+				defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(procs))
+
+				c, closeFn := cc.new()
+				defer closeFn()
+				for i := 0; i < benchKeys/2; i++ {
+					c.Set(keys[i], i)
+				}
+
+				var seq atomic.Uint64
+				b.ResetTimer()
+				b.RunParallel(func(pb *testing.PB) {
+					// every goroutine walks the keys with its own stride to avoid hitting the same keys in lockstep
+					i := seq.Add(7919)
+					for pb.Next() {
+						i += 7919
+						k := keys[i%benchKeys]
+						if i%10 == 0 {
+							c.Set(k, int(i))
+						} else {
+							c.Get(k)
+						}
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package cache
+
+import (
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const snapshotVersion = 1
+
+var (
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+	ErrSnapshotInvalid = errors.New("invalid snapshot")
+)
+
+// Encoder writes one snapshot record, both *gob.Encoder and *json.Encoder implement it
+type Encoder interface {
+	Encode(v any) error
+}
+
+// Decoder reads one snapshot record, both *gob.Decoder and *json.Decoder implement it
+type Decoder interface {
+	Decode(v any) error
+}
+
+// Codec defines how keys and values are written to a snapshot
+type Codec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// GobCodec writes snapshots with encoding/gob, interface values must be registered with gob.Register
+type GobCodec struct{}
+
+func (GobCodec) NewEncoder(w io.Writer) Encoder { return gob.NewEncoder(w) }
+func (GobCodec) NewDecoder(r io.Reader) Decoder { return gob.NewDecoder(r) }
+
+// JSONCodec writes snapshots as a stream of JSON objects, one per line
+type JSONCodec struct{}
+
+func (JSONCodec) NewEncoder(w io.Writer) Encoder { return json.NewEncoder(w) }
+func (JSONCodec) NewDecoder(r io.Reader) Decoder { return json.NewDecoder(r) }
+
+type snapshotHeader struct {
+	Version int
+	Count   int
+}
+
+// snapshotRecord is one cache entry, records are written from the least to the most recently used
+// one, so loading them in order restores the LRU order
+type snapshotRecord[K comparable, V any] struct {
+	Key       K
+	Value     V
+	ExpiresAt time.Time // absolute, so time between Save and Load counts, zero means the entry never expires
+}
+
+// snapshotter is a cache which can export and import its entries
+type snapshotter[K comparable, V any] interface {
+	records() []snapshotRecord[K, V]
+	restore(records []snapshotRecord[K, V])
+}
+
+func save[K comparable, V any](s snapshotter[K, V], w io.Writer, codec Codec) error {
+	records := s.records()
+
+	enc := codec.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion, Count: len(records)}); err != nil {
+		return fmt.Errorf("encode snapshot header: %w", err)
+	}
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return fmt.Errorf("encode snapshot record: %w", err)
+		}
+	}
+	return nil
+}
+
+func load[K comparable, V any](s snapshotter[K, V], r io.Reader, codec Codec) error {
+	dec := codec.NewDecoder(r)
+
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return fmt.Errorf("decode snapshot header: %w", err)
+	}
+	if header.Version != snapshotVersion {
+		return fmt.Errorf("%w: %d", ErrSnapshotVersion, header.Version)
+	}
+
+	if header.Count < 0 {
+		return fmt.Errorf("%w: record count %d", ErrSnapshotInvalid, header.Count)
+	}
+
+	// the count comes from the file, so records are not preallocated from it
+	var records []snapshotRecord[K, V]
+	for i := range header.Count {
+		var rec snapshotRecord[K, V]
+		if err := dec.Decode(&rec); err != nil {
+			return fmt.Errorf("%w: decode record %d: %w", ErrSnapshotInvalid, i, err)
+		}
+		records = append(records, rec)
+	}
+
+	s.restore(records)
+	return nil
+}
+
+// saveFile writes the snapshot to a temp file in the same directory and renames it to path,
+// so readers never see a partially written snapshot
+func saveFile[K comparable, V any](s snapshotter[K, V], path string, codec Codec) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp snapshot: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = save(s, tmp, codec); err != nil {
+		return err
+	}
+	// data must be on disk before rename, otherwise a crash can leave an empty file under the final name
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("sync temp snapshot: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("close temp snapshot: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename snapshot: %w", err)
+	}
+	return nil
+}
+
+func loadFile[K comparable, V any](s snapshotter[K, V], path string, codec Codec) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	return load(s, f, codec)
+}
+
+// snapshotEvery saves the snapshot every interval until ctx is done and then saves it one last time
+func snapshotEvery[K comparable, V any](ctx context.Context, s snapshotter[K, V], path string, interval time.Duration, codec Codec) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := saveFile(s, path, codec); err != nil {
+				// keep trying on the next tick, the previous snapshot is still intact
+				log.Printf("periodic snapshot to %s failed: %v", path, err)
+			}
+		case <-ctx.Done():
+			return saveFile(s, path, codec)
+		}
+	}
+}
+
+// Save writes all live entries to w from the least to the most recently used one with their expiration time
+func (c *LRU[K, V]) Save(w io.Writer, codec Codec) error {
+	return save[K, V](c, w, codec)
+}
+
+// Load adds entries from the snapshot written by Save, entries which expired meanwhile are skipped.
+// Loaded entries become the most recently used ones in the snapshot order.
+func (c *LRU[K, V]) Load(r io.Reader, codec Codec) error {
+	return load[K, V](c, r, codec)
+}
+
+// SaveFile atomically replaces the snapshot file at path
+func (c *LRU[K, V]) SaveFile(path string, codec Codec) error {
+	return saveFile[K, V](c, path, codec)
+}
+
+// LoadFile loads the snapshot file written by SaveFile, a missing file is reported with os.ErrNotExist
+func (c *LRU[K, V]) LoadFile(path string, codec Codec) error {
+	return loadFile[K, V](c, path, codec)
+}
+
+// SnapshotEvery saves the cache to path every interval until ctx is done, then saves it the last time.
+// It blocks, so run it in a goroutine.
+func (c *LRU[K, V]) SnapshotEvery(ctx context.Context, path string, interval time.Duration, codec Codec) error {
+	return snapshotEvery[K, V](ctx, c, path, interval, codec)
+}
+
+func (c *LRU[K, V]) records() []snapshotRecord[K, V] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	records := make([]snapshotRecord[K, V], 0, c.order.len)
+	for elem := c.order.root.prev; elem != &c.order.root; elem = elem.prev {
+		if elem.val.expired(now) {
+			continue
+		}
+		records = append(records, snapshotRecord[K, V]{
+			Key:       elem.val.key,
+			Value:     elem.val.val,
+			ExpiresAt: elem.val.expiresAt,
+		})
+	}
+	return records
+}
+
+func (c *LRU[K, V]) restore(records []snapshotRecord[K, V]) {
+	c.mu.Lock()
+	capacity, weighted, now := c.capacity, c.costFn != nil, c.clock.Now()
+	c.mu.Unlock()
+
+	live := make([]snapshotRecord[K, V], 0, len(records))
+	for _, rec := range records {
+		if rec.ExpiresAt.IsZero() || rec.ExpiresAt.After(now) {
+			live = append(live, rec)
+		}
+	}
+
+	// the oldest records would be evicted by the newer ones anyway
+	if !weighted && capacity > 0 && len(live) > capacity {
+		live = live[len(live)-capacity:]
+	}
+
+	for _, rec := range live {
+		var ttl time.Duration
+		if !rec.ExpiresAt.IsZero() {
+			ttl = rec.ExpiresAt.Sub(now)
+		}
+		c.SetWithTTL(rec.Key, rec.Value, ttl)
+	}
+}
+
+// Save writes live entries of all shards, LRU order is kept inside every shard
+func (s *Sharded[K, V]) Save(w io.Writer, codec Codec) error {
+	return save[K, V](s, w, codec)
+}
+
+// Load adds entries from the snapshot written by Save
+func (s *Sharded[K, V]) Load(r io.Reader, codec Codec) error {
+	return load[K, V](s, r, codec)
+}
+
+// SaveFile atomically replaces the snapshot file at path
+func (s *Sharded[K, V]) SaveFile(path string, codec Codec) error {
+	return saveFile[K, V](s, path, codec)
+}
+
+// LoadFile loads the snapshot file written by SaveFile, a missing file is reported with os.ErrNotExist
+func (s *Sharded[K, V]) LoadFile(path string, codec Codec) error {
+	return loadFile[K, V](s, path, codec)
+}
+
+// SnapshotEvery saves the cache to path every interval until ctx is done, then saves it the last time.
+// It blocks, so run it in a goroutine.
+func (s *Sharded[K, V]) SnapshotEvery(ctx context.Context, path string, interval time.Duration, codec Codec) error {
+	return snapshotEvery[K, V](ctx, s, path, interval, codec)
+}
+
+func (s *Sharded[K, V]) records() []snapshotRecord[K, V] {
+	var records []snapshotRecord[K, V]
+	for _, shard := range s.shards {
+		records = append(records, shard.records()...)
+	}
+	return records
+}
+
+func (s *Sharded[K, V]) restore(records []snapshotRecord[K, V]) {
+	// the shard count or seed may differ from the saved cache, so records are routed again
+	byShard := make(map[*LRU[K, V]][]snapshotRecord[K, V], len(s.shards))
+	for _, rec := range records {
+		shard := s.shard(rec.Key)
+		byShard[shard] = append(byShard[shard], rec)
+	}
+	for shard, shardRecords := range byShard {
+		shard.restore(shardRecords)
+	}
+}
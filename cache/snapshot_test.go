@@ -0,0 +1,230 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type snapshotUser struct {
+	ID   int
+	Name string
+}
+
+func TestLRUSnapshot(t *testing.T) {
+	codecs := []struct {
+		name  string
+		codec Codec
+	}{
+		{"gob", GobCodec{}},
+		{"json", JSONCodec{}},
+	}
+
+	for _, cc := range codecs {
+		t.Run(cc.name, func(t *testing.T) {
+			src := NewLRU[string, snapshotUser](4, time.Hour)
+			clock := NewManualClock(time.Now())
+			src.SetClock(clock)
+			src.Set("a", snapshotUser{1, "Alice"})
+			src.Set("b", snapshotUser{2, "Bob"})
+			src.SetWithTTL("c", snapshotUser{3, "Carol"}, 0)
+			src.SetWithTTL("gone", snapshotUser{4, "Gone"}, time.Nanosecond)
+			src.Get("a") // a becomes the most recent, b the least recent
+
+			clock.Advance(time.Millisecond)
+
+			var buf bytes.Buffer
+			if err := src.Save(&buf, cc.codec); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+
+			dst := NewLRU[string, snapshotUser](3, time.Hour)
+			if err := dst.Load(&buf, cc.codec); err != nil {
+				t.Fatalf("load: %v", err)
+			}
+
+			if dst.Len() != 3 {
+				t.Fatalf("expected expired entries to be skipped, len %d", dst.Len())
+			}
+			if v, ok := dst.Peek("a"); !ok || v.Name != "Alice" {
+				t.Fatalf("expected Alice, got %+v", v)
+			}
+
+			// b is the least recently used one, so it goes first
+			dst.Set("d", snapshotUser{5, "Dave"})
+			if _, ok := dst.Peek("b"); ok {
+				t.Fatal("expected LRU order to be restored and b evicted first")
+			}
+
+			dst.mu.Lock()
+			ttlA := dst.items["a"].val.expiresAt
+			dst.mu.Unlock()
+			if left := time.Until(ttlA); left <= 0 || left > time.Hour {
+				t.Fatalf("expected remaining TTL to be kept, got %s", left)
+			}
+		})
+	}
+}
+
+func TestSnapshotTimeBetweenSaveAndLoad(t *testing.T) {
+	clock := NewManualClock(time.Unix(1000, 0))
+	src := NewLRU[string, int](10, 0)
+	src.SetClock(clock)
+	src.SetWithTTL("short", 1, time.Minute)
+	src.SetWithTTL("long", 2, time.Hour)
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf, GobCodec{}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	// the cache is down for two minutes
+	clock.Advance(2 * time.Minute)
+
+	dst := NewLRU[string, int](10, 0)
+	dst.SetClock(clock)
+	if err := dst.Load(&buf, GobCodec{}); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if _, ok := dst.Get("short"); ok || dst.Len() != 1 {
+		t.Fatalf("expected the entry which expired after save to be skipped, len %d", dst.Len())
+	}
+
+	clock.Advance(time.Hour - 2*time.Minute)
+	if _, ok := dst.Get("long"); !ok {
+		t.Fatal("expected long to live until its saved expiration time")
+	}
+	clock.Advance(time.Second)
+	if _, ok := dst.Get("long"); ok {
+		t.Fatal("expected long to expire at its saved expiration time, not an hour after load")
+	}
+}
+
+func TestSnapshotExpiredBeforeLoad(t *testing.T) {
+	var buf bytes.Buffer
+	enc := JSONCodec{}.NewEncoder(&buf)
+	enc.Encode(snapshotHeader{Version: snapshotVersion, Count: 3})
+	enc.Encode(snapshotRecord[string, int]{Key: "expired", Value: 1, ExpiresAt: time.Now().Add(-time.Minute)})
+	enc.Encode(snapshotRecord[string, int]{Key: "live", Value: 2, ExpiresAt: time.Now().Add(time.Minute)})
+	enc.Encode(snapshotRecord[string, int]{Key: "forever", Value: 3})
+
+	c := NewLRU[string, int](10, 0)
+	if err := c.Load(&buf, JSONCodec{}); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+
+	if _, ok := c.Peek("expired"); ok || c.Len() != 2 {
+		t.Fatalf("expected the entry which expired after save to be skipped, len %d", c.Len())
+	}
+
+	c.mu.Lock()
+	live, forever := c.items["live"].val.expiresAt, c.items["forever"].val.expiresAt
+	c.mu.Unlock()
+	if left := time.Until(live); left <= 0 || left > time.Minute {
+		t.Fatalf("expected the saved expiration time to be kept, got %s left", left)
+	}
+	if !forever.IsZero() {
+		t.Fatalf("expected the entry without TTL to never expire, got %s", forever)
+	}
+}
+
+func TestSnapshotVersion(t *testing.T) {
+	var buf bytes.Buffer
+	GobCodec{}.NewEncoder(&buf).Encode(snapshotHeader{Version: 42})
+
+	err := NewLRU[string, int](1, 0).Load(&buf, GobCodec{})
+	if !errors.Is(err, ErrSnapshotVersion) {
+		t.Fatalf("expected ErrSnapshotVersion, got %v", err)
+	}
+}
+
+func TestSnapshotCorruptHeader(t *testing.T) {
+	cases := map[string]string{
+		"negative count":  `{"Version":1,"Count":-1}`,
+		"huge count":      `{"Version":1,"Count":9223372036854775807}`,
+		"missing records": "{\"Version\":1,\"Count\":2}\n{\"Key\":\"a\",\"Value\":1}\n",
+	}
+
+	for name, data := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := NewLRU[string, int](10, 0)
+			err := c.Load(bytes.NewBufferString(data), JSONCodec{})
+			if !errors.Is(err, ErrSnapshotInvalid) {
+				t.Fatalf("expected ErrSnapshotInvalid, got %v", err)
+			}
+			if c.Len() != 0 {
+				t.Fatalf("expected nothing to be loaded, got %d entries", c.Len())
+			}
+		})
+	}
+}
+
+func TestShardedSnapshot(t *testing.T) {
+	src := NewSharded[int, string](4, 100, 0)
+	for i := 0; i < 50; i++ {
+		src.Set(i, fmt.Sprint(i))
+	}
+
+	var buf bytes.Buffer
+	if err := src.Save(&buf, JSONCodec{}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	dst := NewSharded[int, string](8, 100, 0)
+	if err := dst.Load(&buf, JSONCodec{}); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if v, ok := dst.Get(i); !ok || v != fmt.Sprint(i) {
+			t.Fatalf("expected %d to be restored, got %q", i, v)
+		}
+	}
+}
+
+func TestSnapshotFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cache.snapshot")
+
+	c := NewLRU[string, int](10, 0)
+	if err := c.LoadFile(path, GobCodec{}); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected os.ErrNotExist for cold start, got %v", err)
+	}
+
+	c.Set("a", 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- c.SnapshotEvery(ctx, path, 5*time.Millisecond, GobCodec{})
+	}()
+
+	waitFor(t, func() bool {
+		_, err := os.Stat(path)
+		return err == nil
+	})
+
+	c.Set("b", 2)
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("final snapshot: %v", err)
+	}
+
+	// temp files are renamed, nothing is left behind
+	files, _ := os.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("expected only the snapshot file, got %d files", len(files))
+	}
+
+	warm := NewLRU[string, int](10, 0)
+	if err := warm.LoadFile(path, GobCodec{}); err != nil {
+		t.Fatalf("load file: %v", err)
+	}
+	if warm.Len() != 2 {
+		t.Fatalf("expected warm cache with 2 entries, got %d", warm.Len())
+	}
+}
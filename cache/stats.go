@@ -0,0 +1,151 @@
+package cache
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Stats is a point in time snapshot of cache counters
+type Stats struct {
+	Hits        uint64 // Get calls which found a live entry
+	Misses      uint64 // Get calls which found nothing or an expired entry
+	Evictions   uint64 // entries removed to free capacity
+	Expirations uint64 // entries removed because their TTL has passed
+	Size        int    // number of entries
+}
+
+// HitRatio returns share of hits in all Get calls, zero if there were no calls
+func (s Stats) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+// add sums counters, it is used to aggregate shards
+func (s Stats) add(other Stats) Stats {
+	return Stats{
+		Hits:        s.Hits + other.Hits,
+		Misses:      s.Misses + other.Misses,
+		Evictions:   s.Evictions + other.Evictions,
+		Expirations: s.Expirations + other.Expirations,
+		Size:        s.Size + other.Size,
+	}
+}
+
+// StatsProvider is implemented by every cache in the package
+type StatsProvider interface {
+	Stats() Stats
+}
+
+// counters are updated with atomics, so reading stats never waits for the cache lock
+type counters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+}
+
+// count registers removed entries by the removal reason, deletions are not counted
+func (c *counters) count(reason EvictReason, n int) {
+	switch reason {
+	case EvictCapacity:
+		c.evictions.Add(uint64(n))
+	case EvictExpired:
+		c.expirations.Add(uint64(n))
+	}
+}
+
+func (c *counters) snapshot(size int) Stats {
+	return Stats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		Evictions:   c.evictions.Load(),
+		Expirations: c.expirations.Load(),
+		Size:        size,
+	}
+}
+
+func (c *LRU[K, V]) Stats() Stats {
+	return c.counters.snapshot(c.Len())
+}
+
+// Stats sums stats of all shards
+func (s *Sharded[K, V]) Stats() Stats {
+	var stats Stats
+	for _, shard := range s.shards {
+		stats = stats.add(shard.Stats())
+	}
+	return stats
+}
+
+// Stats returns stats of the underlying store, stale reads are counted as hits
+func (l *Loading[K, V]) Stats() Stats {
+	return l.store.Stats()
+}
+
+func (s *segmented[K, V]) Stats() Stats {
+	return s.counters.snapshot(s.Len())
+}
+
+// MetricsHandler renders stats of registered caches in Prometheus text exposition format
+type MetricsHandler struct {
+	mu     sync.RWMutex
+	caches map[string]StatsProvider
+}
+
+func NewMetricsHandler() *MetricsHandler {
+	return &MetricsHandler{caches: make(map[string]StatsProvider)}
+}
+
+// Register adds the cache under the name which becomes the "cache" label value
+func (h *MetricsHandler) Register(name string, c StatsProvider) {
+	h.mu.Lock()
+	h.caches[name] = c
+	h.mu.Unlock()
+}
+
+var cacheMetrics = []struct {
+	name  string
+	kind  string
+	help  string
+	value func(Stats) string
+}{
+	{"cache_hits_total", "counter", "Number of cache hits.", func(s Stats) string { return fmt.Sprint(s.Hits) }},
+	{"cache_misses_total", "counter", "Number of cache misses.", func(s Stats) string { return fmt.Sprint(s.Misses) }},
+	{"cache_evictions_total", "counter", "Number of entries evicted to free capacity.", func(s Stats) string { return fmt.Sprint(s.Evictions) }},
+	{"cache_expirations_total", "counter", "Number of expired entries.", func(s Stats) string { return fmt.Sprint(s.Expirations) }},
+	{"cache_size", "gauge", "Number of entries in the cache.", func(s Stats) string { return fmt.Sprint(s.Size) }},
+}
+
+// labelEscaper escapes a label value, the text format allows only \\, \" and \n escapes,
+// so unlike %q other bytes, including non-ASCII ones, are written as is
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func (h *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	names := make([]string, 0, len(h.caches))
+	stats := make(map[string]Stats, len(h.caches))
+	for name, c := range h.caches {
+		names = append(names, name)
+		stats[name] = c.Stats()
+	}
+	h.mu.RUnlock()
+
+	// stable output order makes scrapes easy to diff
+	slices.Sort(names)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, m := range cacheMetrics {
+		fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+		for _, name := range names {
+			fmt.Fprintf(w, "%s{cache=\"%s\"} %s\n", m.name, labelEscaper.Replace(name), m.value(stats[name]))
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package cache
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	caches := []struct {
+		name  string
+		cache interface {
+			Interface[int, int]
+			StatsProvider
+		}
+	}{
+		{"LRU", NewLRU[int, int](2, 0)},
+		{"Sharded", NewSharded[int, int](1, 2, 0)},
+		{"WTinyLFU", NewWTinyLFU[int, int](2, 0)},
+		{"ARC", NewARC[int, int](2, 0)},
+		{"TwoQueue", NewTwoQueue[int, int](2, 0)},
+	}
+
+	for _, cc := range caches {
+		t.Run(cc.name, func(t *testing.T) {
+			c := cc.cache
+
+			c.Set(1, 1)
+			c.Get(1)
+			c.Get(2)
+			c.Set(2, 2)
+			c.Set(3, 3)
+			c.Delete(3)
+
+			stats := c.Stats()
+			if stats.Hits != 1 || stats.Misses != 1 {
+				t.Fatalf("expected 1 hit and 1 miss, got %+v", stats)
+			}
+			if stats.Evictions != 1 {
+				t.Fatalf("expected 1 eviction, got %+v", stats)
+			}
+			if stats.Size != c.Len() {
+				t.Fatalf("expected size %d, got %+v", c.Len(), stats)
+			}
+			if stats.HitRatio() != 0.5 {
+				t.Fatalf("expected hit ratio 0.5, got %f", stats.HitRatio())
+			}
+		})
+	}
+
+	t.Run("Expirations", func(t *testing.T) {
+		c := NewLRU[int, int](10, time.Millisecond)
+		clock := NewManualClock(time.Now())
+		c.SetClock(clock)
+		c.Set(1, 1)
+		clock.Advance(5 * time.Millisecond)
+		c.Get(1)
+
+		if stats := c.Stats(); stats.Expirations != 1 || stats.Misses != 1 || stats.Size != 0 {
+			t.Fatalf("expected 1 expiration, got %+v", stats)
+		}
+	})
+
+	t.Run("Concurrent reads of stats", func(t *testing.T) {
+		c := NewSharded[int, int](4, 100, 0)
+
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					c.Set(i%200, i)
+					c.Get(i % 150)
+					c.Stats()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if stats := c.Stats(); stats.Hits+stats.Misses != 4000 {
+			t.Fatalf("expected 4000 reads, got %+v", stats)
+		}
+	})
+}
+
+func TestMetricsHandler(t *testing.T) {
+	users := NewLRU[string, int](10, 0)
+	users.Set("a", 1)
+	users.Get("a")
+	users.Get("b")
+
+	h := NewMetricsHandler()
+	h.Register("users", users)
+	h.Register("orders", NewARC[string, int](10, 0))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE cache_hits_total counter",
+		`cache_hits_total{cache="users"} 1`,
+		`cache_misses_total{cache="users"} 1`,
+		`cache_hits_total{cache="orders"} 0`,
+		"# TYPE cache_size gauge",
+		`cache_size{cache="users"} 1`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("expected line %q in:\n%s", line, body)
+		}
+	}
+
+	// caches are sorted by name
+	if strings.Index(body, `{cache="orders"}`) > strings.Index(body, `{cache="users"}`) {
+		t.Errorf("expected sorted output:\n%s", body)
+	}
+
+	// only backslash, quote and newline are escaped in label values
+	h.Register("ключ \"a\\b\"\nnext\t", NewLRU[string, int](10, 0))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if line := `cache_size{cache="ключ \"a\\b\"\nnext` + "\t" + `"} 0`; !strings.Contains(rec.Body.String(), line+"\n") {
+		t.Errorf("expected line %q in:\n%s", line, rec.Body.String())
+	}
+}
@@ -0,0 +1,88 @@
+//go:build ignore
+
+// record_trace records identifier lookups of a Go front end, which is a classic symbol cache workload.
+// It scans all non-test Go files of the package directories in file name order and writes every identifier
+// on its own line. Traces in this directory were recorded with
+//
+//	go run record_trace.go -o go_types.trace $(go env GOROOT)/src/go/types
+//	go run record_trace.go -o net_http.trace $(go env GOROOT)/src/net/http
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+func main() {
+	out := flag.String("o", "", "output trace file")
+	flag.Parse()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	fmt.Fprintf(w, "# identifier lookups of %s, %s\n", strings.Join(packages(flag.Args()), " "), runtime.Version())
+
+	for _, dir := range flag.Args() {
+		files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		sort.Strings(files)
+
+		for _, name := range files {
+			if strings.HasSuffix(name, "_test.go") {
+				continue
+			}
+			if err := record(w, name); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// packages returns import paths of the package directories
+func packages(dirs []string) []string {
+	src := filepath.Join(runtime.GOROOT(), "src") + string(filepath.Separator)
+	paths := make([]string, len(dirs))
+	for i, dir := range dirs {
+		paths[i] = filepath.ToSlash(strings.TrimPrefix(filepath.Clean(dir), src))
+	}
+	return paths
+}
+
+func record(w *bufio.Writer, name string) error {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	fset := token.NewFileSet()
+	var s scanner.Scanner
+	s.Init(fset.AddFile(name, -1, len(src)), src, nil, 0)
+	for {
+		_, tok, lit := s.Scan()
+		if tok == token.EOF {
+			return nil
+		}
+		if tok == token.IDENT {
+			fmt.Fprintln(w, lit)
+		}
+	}
+}
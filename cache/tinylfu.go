@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+
+	bloom "go-helloworld/filter/bloom"
+	countmin "go-helloworld/sketch/countmin"
+)
+
+const (
+	sketchDepth = 4  // number of count-min rows
+	counterMax  = 15 // counters saturate, so old popularity fades after a few halvings
+)
+
+// tinyLFU is a frequency based admission policy.
+// The doorkeeper bloom filter absorbs keys seen only once, so one-off scans do not pollute the sketch.
+// After sampleSize accesses all counters are halved and the doorkeeper is cleared.
+type tinyLFU struct {
+	sketch     *countmin.CountMin
+	doorkeeper *bloom.BloomFilter
+	additions  int
+	sampleSize int
+}
+
+func newTinyLFU(capacity int) *tinyLFU {
+	sampleSize := 10 * max(capacity, 1)
+	return &tinyLFU{
+		sketch:     countmin.NewCountMin(2*max(capacity, 16), sketchDepth), // a sparse row keeps one-off keys off popular counters
+		doorkeeper: bloom.NewBloomFilter(uint64(sampleSize), 8, 4),
+		sampleSize: sampleSize,
+	}
+}
+
+// record registers one access of the key hash
+func (t *tinyLFU) record(h uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], h)
+
+	if !t.doorkeeper.Contains(buf[:]) {
+		t.doorkeeper.Add(buf[:])
+	} else if t.sketch.EstimateHash(h) < counterMax {
+		t.sketch.AddHash(h, 1)
+	}
+
+	t.additions++
+	if t.additions >= t.sampleSize {
+		t.age()
+	}
+}
+
+// frequency returns estimated number of accesses of the key hash
+func (t *tinyLFU) frequency(h uint64) int {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], h)
+
+	freq := int(t.sketch.EstimateHash(h))
+	if t.doorkeeper.Contains(buf[:]) {
+		freq++
+	}
+	return freq
+}
+
+// admit reports whether the candidate is more popular than the victim it wants to replace
+func (t *tinyLFU) admit(candidate, victim uint64) bool {
+	return t.frequency(candidate) > t.frequency(victim)
+}
+
+func (t *tinyLFU) age() {
+	t.sketch.Halve()
+	t.doorkeeper.Reset()
+	t.additions /= 2
+}
+
+// AdmissionPolicy decides whether a new key may replace the eviction victim of a full cache.
+// The cache calls it with its lock held, so one policy must not be shared between caches.
+type AdmissionPolicy[K comparable] interface {
+	Record(k K)                     // registers one access of the key
+	Admit(candidate, victim K) bool // reports whether the candidate should replace the victim
+}
+
+// TinyLFU is AdmissionPolicy which admits a new key only if it was accessed more often than the victim,
+// so one-off scans can not flush frequently used entries. It is not safe for concurrent use.
+type TinyLFU[K comparable] struct {
+	seed   maphash.Seed
+	policy *tinyLFU
+}
+
+// NewTinyLFU creates admission policy for a cache which holds up to capacity entries
+func NewTinyLFU[K comparable](capacity int) *TinyLFU[K] {
+	return &TinyLFU[K]{
+		seed:   maphash.MakeSeed(),
+		policy: newTinyLFU(capacity),
+	}
+}
+
+func (p *TinyLFU[K]) Record(k K) {
+	p.policy.record(maphash.Comparable(p.seed, k))
+}
+
+func (p *TinyLFU[K]) Admit(candidate, victim K) bool {
+	return p.policy.admit(maphash.Comparable(p.seed, candidate), maphash.Comparable(p.seed, victim))
+}
@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"bufio"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readTrace reads a recorded trace from testdata, one key per line, lines starting with # are comments.
+// See testdata/record_trace.go for how traces are recorded.
+func readTrace(t *testing.T, name string) []string {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" && !strings.HasPrefix(line, "#") {
+			keys = append(keys, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// loopHotTrace is a hot set of 100 keys interleaved with a loop over 400 keys which does not fit into the cache
+func loopHotTrace(seed int64) []uint64 {
+	r := rand.New(rand.NewSource(seed))
+
+	keys := make([]uint64, 0, 16000)
+	for i := range 8000 {
+		keys = append(keys, uint64(r.Intn(100)), uint64(1000+i%400))
+	}
+	return keys
+}
+
+// shiftingTrace is a hot set of 150 keys which moves every 3000 accesses, with 20% of accesses to cold keys
+func shiftingTrace(seed int64) []uint64 {
+	r := rand.New(rand.NewSource(seed))
+
+	keys := make([]uint64, 15000)
+	for i := range keys {
+		if r.Float64() < 0.2 {
+			keys[i] = uint64(100_000 + r.Intn(100_000))
+			continue
+		}
+		keys[i] = uint64((i/3000)*150 + r.Intn(150))
+	}
+	return keys
+}
+
+// policyRatios replays keys on every policy with the same capacity and returns hit ratios by policy name
+func policyRatios[K comparable](capacity int, keys []K) map[string]float64 {
+	return map[string]float64{
+		"LRU":      hitRatio(NewLRU[K, K](capacity, 0), keys),
+		"ARC":      hitRatio(NewARC[K, K](capacity, 0), keys),
+		"2Q":       hitRatio(NewTwoQueue[K, K](capacity, 0), keys),
+		"WTinyLFU": hitRatio(NewWTinyLFU[K, K](capacity, 0), keys),
+	}
+}
+
+func TestRecordedTraceHitRatio(t *testing.T) {
+	const capacity = 100
+
+	// identifier lookups are recency friendly, LRU is hard to beat there,
+	// but scan resistance must not cost ARC and 2Q more than this
+	const tolerance = 0.02
+
+	for _, name := range []string{"go_types.trace", "net_http.trace"} {
+		t.Run(name, func(t *testing.T) {
+			ratios := policyRatios(capacity, readTrace(t, name))
+			t.Logf("LRU %.3f, ARC %.3f, 2Q %.3f, W-TinyLFU %.3f", ratios["LRU"], ratios["ARC"], ratios["2Q"], ratios["WTinyLFU"])
+
+			for _, policy := range []string{"ARC", "2Q"} {
+				if ratios[policy] < ratios["LRU"]-tolerance {
+					t.Errorf("expected %s hit ratio %.3f to be within %.2f of LRU %.3f", policy, ratios[policy], tolerance, ratios["LRU"])
+				}
+			}
+		})
+	}
+}
+
+func TestScanTraceHitRatio(t *testing.T) {
+	const capacity = 200
+
+	tests := []struct {
+		name     string
+		keys     []uint64
+		policies []string
+		// scan resistant policies must beat LRU by at least this margin
+		margin float64
+	}{
+		{"zipf_scan", zipfWorkload(1, 20_000, 2000, true), []string{"ARC", "2Q", "WTinyLFU"}, 0.02},
+		{"loop_hot", loopHotTrace(1), []string{"ARC", "2Q", "WTinyLFU"}, 0.05},
+		{"shifting", shiftingTrace(1), []string{"ARC", "2Q"}, 0.02},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ratios := policyRatios(capacity, tt.keys)
+			t.Logf("LRU %.3f, ARC %.3f, 2Q %.3f, W-TinyLFU %.3f", ratios["LRU"], ratios["ARC"], ratios["2Q"], ratios["WTinyLFU"])
+
+			for _, policy := range tt.policies {
+				if ratios[policy] < ratios["LRU"]+tt.margin {
+					t.Errorf("expected %s hit ratio %.3f to beat LRU %.3f by %.2f", policy, ratios[policy], ratios["LRU"], tt.margin)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package cache
+
+import "time"
+
+const (
+	segmentA1in segment = iota // FIFO of entries seen once
+	segmentAm                  // LRU of entries seen again after they left A1in
+)
+
+// TwoQueue is a thread-safe 2Q cache.
+// New entries go to the A1in FIFO. Keys pushed out of A1in are remembered in the A1out ghost list, and only
+// an entry requested again while its key is in A1out gets to the main Am LRU. Entries seen once by a scan
+// pass through A1in and never reach Am.
+type TwoQueue[K comparable, V any] struct {
+	segmented[K, V]
+	capacity int
+	kin      int // A1in size, 25% of capacity
+	a1out    *ghostList[K]
+}
+
+// NewTwoQueue creates 2Q cache which holds up to capacity entries and remembers half of capacity evicted keys.
+// ttl is the default time to live for entries, zero ttl means entries never expire.
+func NewTwoQueue[K comparable, V any](capacity int, ttl time.Duration) *TwoQueue[K, V] {
+	capacity = max(capacity, 1)
+	kin := max(capacity/4, 1)
+
+	return &TwoQueue[K, V]{
+		segmented: newSegmented[K, V](ttl, kin, capacity),
+		capacity:  capacity,
+		kin:       kin,
+		a1out:     newGhostList[K](max(capacity/2, 1)),
+	}
+}
+
+func (c *TwoQueue[K, V]) Get(k K) (V, bool) {
+	c.mu.Lock()
+	elem, expired := c.lookup(k)
+	if elem == nil {
+		c.unlock(expired, EvictExpired)
+		c.countGet(false)
+		var zeroVal V
+		return zeroVal, false
+	}
+
+	// A1in is a FIFO, correlated hits right after insert must not promote the entry
+	if elem.val.segment == segmentAm {
+		c.segments[segmentAm].moveToFront(elem)
+	}
+	v := elem.val.val
+	c.mu.Unlock()
+	c.countGet(true)
+	return v, true
+}
+
+func (c *TwoQueue[K, V]) Set(k K, v V) {
+	c.mu.Lock()
+	expiresAt := c.expiresAt()
+	if elem, exists := c.items[k]; exists {
+		elem.val.val = v
+		elem.val.expiresAt = expiresAt
+		if elem.val.segment == segmentAm {
+			c.segments[segmentAm].moveToFront(elem)
+		}
+		c.mu.Unlock()
+		return
+	}
+
+	evicted := c.reclaim()
+	ent := segmentEntry[K, V]{key: k, val: v, expiresAt: expiresAt}
+	if c.a1out.contains(k) {
+		c.a1out.remove(k)
+		c.push(segmentAm, ent)
+	} else {
+		c.push(segmentA1in, ent)
+	}
+	c.unlock(evicted, EvictCapacity)
+}
+
+// reclaim frees one slot for a new entry if the cache is full, must be called with c.mu held
+func (c *TwoQueue[K, V]) reclaim() []segmentEntry[K, V] {
+	if len(c.items) < c.capacity {
+		return nil
+	}
+
+	a1in := c.segments[segmentA1in]
+	if a1in.len >= c.kin || c.segments[segmentAm].len == 0 {
+		ent := c.removeElement(a1in.back())
+		c.a1out.add(ent.key)
+		return []segmentEntry[K, V]{ent}
+	}
+
+	return []segmentEntry[K, V]{c.removeElement(c.segments[segmentAm].back())}
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTwoQueue(t *testing.T) {
+	t.Run("Set Get Delete", func(t *testing.T) {
+		c := NewTwoQueue[string, int](10, 0)
+
+		c.Set("a", 1)
+		if v, ok := c.Get("a"); !ok || v != 1 {
+			t.Fatalf("expected a = 1, got %d (%v)", v, ok)
+		}
+		if !c.Delete("a") || c.Len() != 0 {
+			t.Fatal("expected a to be deleted")
+		}
+	})
+
+	t.Run("Only A1out hits reach Am", func(t *testing.T) {
+		c := NewTwoQueue[int, int](8, 0)
+
+		for i := 0; i < 8; i++ {
+			c.Set(i, i)
+		}
+		// 0 was pushed out of A1in to A1out, so the next Set puts it to Am
+		c.Set(8, 8)
+		if !c.a1out.contains(0) {
+			t.Fatal("expected 0 to be remembered in A1out")
+		}
+
+		c.Set(0, 0)
+		if c.items[0].val.segment != segmentAm {
+			t.Fatal("expected 0 to be promoted to Am")
+		}
+
+		// a scan can not push the entry out of Am
+		for i := 100; i < 200; i++ {
+			c.Set(i, i)
+		}
+		if _, ok := c.Peek(0); !ok {
+			t.Fatal("expected Am entry to survive the scan")
+		}
+	})
+
+	t.Run("Capacity is respected", func(t *testing.T) {
+		c := NewTwoQueue[string, int](20, 0)
+
+		evicted := 0
+		c.OnEvict(func(k string, v int, reason EvictReason) { evicted++ })
+
+		for i := 0; i < 500; i++ {
+			key := fmt.Sprintf("key-%d", i%60)
+			if _, ok := c.Get(key); !ok {
+				c.Set(key, i)
+			}
+			if c.Len() > 20 {
+				t.Fatalf("cache exceeded capacity: %d", c.Len())
+			}
+		}
+		if evicted == 0 {
+			t.Fatal("expected evictions")
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package cache
+
+import (
+	"runtime"
+	"sync"
+	"unique"
+	"weak"
+)
+
+// Weak is a thread-safe canonicalizing cache which holds values weakly.
+// It maps a key to a single shared *V while anyone else uses the value. The cache itself does not keep
+// values alive: once the value is collected by GC, its map entry is removed by a cleanup.
+type Weak[K comparable, V any] struct {
+	mu       sync.Mutex
+	items    map[K]weak.Pointer[V]
+	counters counters
+}
+
+func NewWeak[K comparable, V any]() *Weak[K, V] {
+	return &Weak[K, V]{items: make(map[K]weak.Pointer[V])}
+}
+
+// weakCleanup is the argument of the cleanup, it must not point to the value or the value is never collected
+type weakCleanup[K comparable, V any] struct {
+	key K
+	ptr weak.Pointer[V]
+}
+
+// Get returns the value if it is still alive
+func (c *Weak[K, V]) Get(k K) (*V, bool) {
+	c.mu.Lock()
+	v := c.items[k].Value() // Value of the zero weak.Pointer is nil
+	c.mu.Unlock()
+
+	if v == nil {
+		c.counters.misses.Add(1)
+		return nil, false
+	}
+	c.counters.hits.Add(1)
+	return v, true
+}
+
+// GetOrSet returns the canonical value for the key.
+// If the key has a live value, it is returned with true, otherwise v becomes the canonical value.
+// A nil v is returned with false and is not stored, as there is nothing to hold weakly.
+func (c *Weak[K, V]) GetOrSet(k K, v *V) (*V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existing := c.items[k].Value(); existing != nil {
+		c.counters.hits.Add(1)
+		return existing, true
+	}
+	c.counters.misses.Add(1)
+	c.set(k, v)
+	return v, false
+}
+
+// GetOrCreate returns the canonical value for the key and calls create only if there is no live value.
+// create is called under the cache lock, so it must not use the cache. If create returns nil,
+// nil is returned and nothing is stored, so the next call calls create again.
+func (c *Weak[K, V]) GetOrCreate(k K, create func() *V) *V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if existing := c.items[k].Value(); existing != nil {
+		c.counters.hits.Add(1)
+		return existing
+	}
+	c.counters.misses.Add(1)
+	v := create()
+	c.set(k, v)
+	return v
+}
+
+// set stores weak pointer to v and registers the cleanup, must be called with c.mu held.
+// nil is skipped, runtime.AddCleanup panics on it.
+func (c *Weak[K, V]) set(k K, v *V) {
+	if v == nil {
+		return
+	}
+	ptr := weak.Make(v)
+	c.items[k] = ptr
+	runtime.AddCleanup(v, c.cleanup, weakCleanup[K, V]{key: k, ptr: ptr})
+}
+
+// cleanup removes the entry of a collected value. The key could be set again with a new value
+// before the cleanup has run, so the entry is removed only if it still points to the collected value.
+func (c *Weak[K, V]) cleanup(arg weakCleanup[K, V]) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ptr, ok := c.items[arg.key]; ok && ptr == arg.ptr {
+		delete(c.items, arg.key)
+		c.counters.evictions.Add(1)
+	}
+}
+
+// Delete removes the key and reports whether it had a live value.
+// The value itself is not affected and stays valid for those who use it.
+func (c *Weak[K, V]) Delete(k K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ptr, ok := c.items[k]
+	if !ok {
+		return false
+	}
+	delete(c.items, k)
+	return ptr.Value() != nil
+}
+
+// Len returns number of entries, entries of collected values are counted until their cleanup has run
+func (c *Weak[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.items)
+}
+
+// Stats counts entries removed after their values were collected as evictions
+func (c *Weak[K, V]) Stats() Stats {
+	return c.counters.snapshot(c.Len())
+}
+
+// Intern returns the canonical copy of v. Equal values share the same memory, e.g. equal strings
+// point to the same bytes, and the canonical copy is freed when nobody uses it anymore.
+func Intern[T comparable](v T) T {
+	return unique.Make(v).Value()
+}
+
+// InternBytes returns the canonical string with the content of b
+func InternBytes(b []byte) string {
+	return unique.Make(string(b)).Value()
+}
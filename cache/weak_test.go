@@ -0,0 +1,147 @@
+package cache
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+type weakValue struct {
+	id   int
+	data [64]byte
+}
+
+// setWeak stores a value which is referenced only by the cache
+//
+//go:noinline
+func setWeak(c *Weak[string, weakValue], k string, id int) {
+	c.GetOrSet(k, &weakValue{id: id})
+}
+
+// waitForGC runs GC until cond is true, cleanups run in a separate goroutine after the collection
+func waitForGC(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	waitFor(t, func() bool {
+		runtime.GC()
+		return cond()
+	})
+}
+
+func TestWeak(t *testing.T) {
+	t.Run("Returns the canonical value", func(t *testing.T) {
+		c := NewWeak[string, weakValue]()
+
+		first := &weakValue{id: 1}
+		if v, loaded := c.GetOrSet("a", first); loaded || v != first {
+			t.Fatal("expected the first value to become canonical")
+		}
+		if v, loaded := c.GetOrSet("a", &weakValue{id: 2}); !loaded || v != first {
+			t.Fatal("expected the existing value to be returned")
+		}
+		if v := c.GetOrCreate("a", func() *weakValue { t.Fatal("unexpected create"); return nil }); v != first {
+			t.Fatal("expected GetOrCreate to return the existing value")
+		}
+		if v, ok := c.Get("a"); !ok || v != first {
+			t.Fatal("expected Get to return the existing value")
+		}
+
+		runtime.GC()
+		if v, ok := c.Get("a"); !ok || v.id != 1 {
+			t.Fatal("expected a value in use to survive GC")
+		}
+		runtime.KeepAlive(first)
+	})
+
+	t.Run("Entries disappear after GC", func(t *testing.T) {
+		c := NewWeak[string, weakValue]()
+		for i, k := range []string{"a", "b", "c"} {
+			setWeak(c, k, i)
+		}
+		if c.Len() != 3 {
+			t.Fatalf("expected 3 entries, got %d", c.Len())
+		}
+
+		waitForGC(t, func() bool { return c.Len() == 0 })
+
+		if _, ok := c.Get("a"); ok {
+			t.Fatal("expected a to be collected")
+		}
+		if s := c.Stats(); s.Evictions != 3 {
+			t.Fatalf("expected 3 evictions, got %d", s.Evictions)
+		}
+	})
+
+	t.Run("Cleanup keeps the newer value", func(t *testing.T) {
+		c := NewWeak[string, weakValue]()
+		setWeak(c, "a", 1)
+
+		// the old value is collected, but its cleanup may not have run yet
+		waitForGC(t, func() bool { _, ok := c.Get("a"); return !ok })
+		newer := c.GetOrCreate("a", func() *weakValue { return &weakValue{id: 2} })
+
+		// give the old cleanup a chance to run
+		for i := 0; i < 3; i++ {
+			runtime.GC()
+			time.Sleep(time.Millisecond)
+		}
+		if v, ok := c.Get("a"); !ok || v != newer {
+			t.Fatal("expected the newer value to stay in the cache")
+		}
+		runtime.KeepAlive(newer)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		c := NewWeak[string, weakValue]()
+		v := &weakValue{id: 1}
+		c.GetOrSet("a", v)
+
+		if !c.Delete("a") {
+			t.Fatal("expected Delete to report the live value")
+		}
+		if c.Delete("a") {
+			t.Fatal("expected second Delete to report nothing")
+		}
+		if v.id != 1 {
+			t.Fatal("expected the value to stay valid")
+		}
+	})
+
+	t.Run("Nil values are not stored", func(t *testing.T) {
+		c := NewWeak[string, weakValue]()
+
+		if v, loaded := c.GetOrSet("a", nil); v != nil || loaded {
+			t.Fatalf("expected nil and false, got %v and %v", v, loaded)
+		}
+		if v := c.GetOrCreate("a", func() *weakValue { return nil }); v != nil {
+			t.Fatalf("expected nil, got %v", v)
+		}
+		if c.Len() != 0 {
+			t.Fatalf("expected no entries, got %d", c.Len())
+		}
+
+		v := &weakValue{id: 1}
+		if got := c.GetOrCreate("a", func() *weakValue { return v }); got != v {
+			t.Fatal("expected create to be called again after nil")
+		}
+	})
+}
+
+func TestIntern(t *testing.T) {
+	a := InternBytes([]byte("hello world"))
+	b := Intern(strings.Repeat("hello ", 1) + "world")
+
+	if a != b {
+		t.Fatalf("expected equal strings, got %q and %q", a, b)
+	}
+	if unsafe.StringData(a) != unsafe.StringData(b) {
+		t.Fatal("expected interned strings to share memory")
+	}
+
+	type point struct{ x, y int }
+	if Intern(point{1, 2}) != (point{1, 2}) {
+		t.Fatal("expected interned struct to be equal to the original")
+	}
+}
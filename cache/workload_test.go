@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"math/rand"
+)
+
+// replayCache is the subset of cache methods needed to replay a workload
+type replayCache[K comparable] interface {
+	Get(k K) (K, bool)
+	Set(k K, v K)
+}
+
+// hitRatio replays keys as read-through accesses and returns the share of hits
+func hitRatio[K comparable](c replayCache[K], keys []K) float64 {
+	hits := 0
+	for _, k := range keys {
+		if _, ok := c.Get(k); ok {
+			hits++
+			continue
+		}
+		c.Set(k, k)
+	}
+	return float64(hits) / float64(len(keys))
+}
+
+// zipfWorkload generates n keys from Zipf distribution over keySpace keys.
+// With scans enabled every 10th block of 1000 accesses is replaced by a scan of unique keys.
+func zipfWorkload(seed int64, n int, keySpace uint64, scans bool) []uint64 {
+	r := rand.New(rand.NewSource(seed))
+	zipf := rand.NewZipf(r, 1.1, 1, keySpace-1)
+
+	keys := make([]uint64, n)
+	scanKey := keySpace
+	for i := range keys {
+		if scans && (i/1000)%10 == 9 {
+			keys[i] = scanKey
+			scanKey++
+			continue
+		}
+		keys[i] = zipf.Uint64()
+	}
+	return keys
+}
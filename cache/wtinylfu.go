@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"hash/maphash"
+	"time"
+)
+
+const (
+	segmentWindow    segment = iota // small LRU for new entries
+	segmentProbation                // main region entries which were not hit again yet
+	segmentProtected                // main region entries which were hit at least twice
+)
+
+// WTinyLFU is a thread-safe cache with the W-TinyLFU policy.
+// New entries get to the window LRU (1% of capacity). When the window is full, its oldest entry becomes
+// a candidate for the main segmented LRU and is admitted only if the frequency sketch says it is more
+// popular than the main region victim, so one-off scans can not flush frequently used entries.
+type WTinyLFU[K comparable, V any] struct {
+	segmented[K, V]
+	seed   maphash.Seed
+	policy *tinyLFU
+}
+
+// NewWTinyLFU creates W-TinyLFU cache which holds up to capacity entries.
+// ttl is the default time to live for entries, zero ttl means entries never expire.
+func NewWTinyLFU[K comparable, V any](capacity int, ttl time.Duration) *WTinyLFU[K, V] {
+	capacity = max(capacity, 2)
+
+	windowCap := max(capacity/100, 1)
+	mainCap := capacity - windowCap
+	protectedCap := mainCap * 8 / 10
+	probationCap := mainCap - protectedCap
+
+	return &WTinyLFU[K, V]{
+		segmented: newSegmented[K, V](ttl, windowCap, probationCap, protectedCap),
+		seed:      maphash.MakeSeed(),
+		policy:    newTinyLFU(capacity),
+	}
+}
+
+func (c *WTinyLFU[K, V]) Set(k K, v V) {
+	h := maphash.Comparable(c.seed, k)
+
+	c.mu.Lock()
+	expiresAt := c.expiresAt()
+	c.policy.record(h)
+
+	if elem, exists := c.items[k]; exists {
+		elem.val.val = v
+		elem.val.expiresAt = expiresAt
+		c.onHit(elem)
+		c.mu.Unlock()
+		return
+	}
+
+	c.push(segmentWindow, segmentEntry[K, V]{key: k, val: v, hash: h, expiresAt: expiresAt})
+
+	var evicted []segmentEntry[K, V]
+	if window := c.segments[segmentWindow]; window.len > window.capacity {
+		evicted = c.evictFromWindow()
+	}
+	c.unlock(evicted, EvictCapacity)
+}
+
+func (c *WTinyLFU[K, V]) Get(k K) (V, bool) {
+	h := maphash.Comparable(c.seed, k)
+
+	c.mu.Lock()
+	c.policy.record(h)
+
+	elem, expired := c.lookup(k)
+	if elem == nil {
+		c.unlock(expired, EvictExpired)
+		c.countGet(false)
+		var zeroVal V
+		return zeroVal, false
+	}
+
+	c.onHit(elem)
+	v := elem.val.val
+	c.mu.Unlock()
+	c.countGet(true)
+	return v, true
+}
+
+// onHit updates position of the entry after access, must be called with c.mu held
+func (c *WTinyLFU[K, V]) onHit(elem *node[segmentEntry[K, V]]) {
+	switch elem.val.segment {
+	case segmentWindow, segmentProtected:
+		c.segments[elem.val.segment].moveToFront(elem)
+	case segmentProbation:
+		// the second hit promotes the entry to the protected segment
+		c.relink(elem, segmentProtected)
+
+		// protected overflow goes back to probation, where it competes for the space again
+		protected := c.segments[segmentProtected]
+		if protected.len > protected.capacity {
+			c.relink(protected.back(), segmentProbation)
+		}
+	}
+}
+
+// evictFromWindow moves the oldest window entry to the main region if it wins against the main victim,
+// must be called with c.mu held. It returns entries which left the cache.
+func (c *WTinyLFU[K, V]) evictFromWindow() []segmentEntry[K, V] {
+	candidate := c.segments[segmentWindow].back()
+
+	probation := c.segments[segmentProbation]
+	protected := c.segments[segmentProtected]
+	if probation.len+protected.len < probation.capacity+protected.capacity {
+		c.relink(candidate, segmentProbation)
+		return nil
+	}
+
+	victim := probation.back()
+	if victim == nil {
+		victim = protected.back()
+	}
+
+	if c.policy.admit(candidate.val.hash, victim.val.hash) {
+		ent := c.removeElement(victim)
+		c.relink(candidate, segmentProbation)
+		return []segmentEntry[K, V]{ent}
+	}
+
+	return []segmentEntry[K, V]{c.removeElement(candidate)}
+}
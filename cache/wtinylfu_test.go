@@ -0,0 +1,241 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTinyLFUFrequency(t *testing.T) {
+	p := newTinyLFU(64)
+
+	for i := 0; i < 10; i++ {
+		p.record(1)
+	}
+	for i := 0; i < 40; i++ {
+		p.record(2)
+	}
+
+	// the first access of a key only sets the doorkeeper
+	if got := p.frequency(1); got != 10 {
+		t.Fatalf("expected frequency 10, got %d", got)
+	}
+	if got := p.frequency(2); got != counterMax+1 {
+		t.Fatalf("expected frequency to saturate at %d, got %d", counterMax+1, got)
+	}
+
+	p.age()
+	if got := p.frequency(2); got != counterMax/2 {
+		t.Fatalf("expected halved frequency %d without the doorkeeper, got %d", counterMax/2, got)
+	}
+}
+
+func TestTinyLFUAdmission(t *testing.T) {
+	p := newTinyLFU(100)
+
+	// the first access is absorbed by the doorkeeper
+	p.record(1)
+	if got := p.frequency(1); got != 1 {
+		t.Fatalf("expected frequency 1, got %d", got)
+	}
+
+	for i := 0; i < 5; i++ {
+		p.record(2)
+	}
+	if !p.admit(2, 1) {
+		t.Fatal("expected popular key to be admitted")
+	}
+	if p.admit(1, 2) {
+		t.Fatal("expected rare key to be rejected")
+	}
+
+	// aging clears the doorkeeper and halves the sketch
+	for i := 0; i < p.sampleSize; i++ {
+		p.record(uint64(1000 + i))
+	}
+	if got := p.frequency(2); got > 3 {
+		t.Fatalf("expected frequency to decay after aging, got %d", got)
+	}
+}
+
+func TestLRUAdmission(t *testing.T) {
+	t.Run("Scan does not flush hot entries", func(t *testing.T) {
+		c := NewLRU[string, int](100, 0)
+		c.SetAdmission(NewTinyLFU[string](100))
+
+		for round := 0; round < 5; round++ {
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("hot-%d", i)
+				if _, ok := c.Get(key); !ok {
+					c.Set(key, i)
+				}
+			}
+		}
+
+		for i := 0; i < 1000; i++ {
+			c.Set(fmt.Sprintf("scan-%d", i), i)
+		}
+
+		hot := 0
+		for i := 0; i < 50; i++ {
+			if _, ok := c.Peek(fmt.Sprintf("hot-%d", i)); ok {
+				hot++
+			}
+		}
+		// the victim is always the least recently used hot entry, so a sketch collision costs one of them
+		if hot < 35 {
+			t.Fatalf("expected LRU with TinyLFU admission to keep hot entries, kept %d of 50", hot)
+		}
+		if c.Len() != 100 {
+			t.Fatalf("expected full cache, got %d entries", c.Len())
+		}
+	})
+
+	t.Run("Updates are not rejected", func(t *testing.T) {
+		c := NewLRU[string, int](1, 0)
+		c.SetAdmission(NewTinyLFU[string](1))
+
+		c.Set("a", 1)
+		c.Set("a", 2)
+		if v, ok := c.Get("a"); !ok || v != 2 {
+			t.Fatalf("expected a = 2, got %d (%v)", v, ok)
+		}
+	})
+
+	t.Run("Zipf hit ratio beats LRU", func(t *testing.T) {
+		keys := zipfWorkload(42, 100_000, 100_000, true)
+
+		admitted := NewLRU[uint64, uint64](1000, 0)
+		admitted.SetAdmission(NewTinyLFU[uint64](1000))
+		tiny := hitRatio(admitted, keys)
+		lru := hitRatio(NewLRU[uint64, uint64](1000, 0), keys)
+
+		t.Logf("hit ratio: LRU+TinyLFU %.3f, LRU %.3f", tiny, lru)
+		if tiny <= lru {
+			t.Fatalf("expected LRU+TinyLFU hit ratio %.3f to beat LRU %.3f", tiny, lru)
+		}
+	})
+}
+
+func TestWTinyLFU(t *testing.T) {
+	t.Run("Set Get Delete", func(t *testing.T) {
+		c := NewWTinyLFU[string, int](100, 0)
+
+		c.Set("a", 1)
+		if v, ok := c.Get("a"); !ok || v != 1 {
+			t.Fatalf("expected a = 1, got %d (%v)", v, ok)
+		}
+
+		c.Set("a", 2)
+		if v, ok := c.Peek("a"); !ok || v != 2 {
+			t.Fatalf("expected a = 2, got %d (%v)", v, ok)
+		}
+
+		if !c.Delete("a") || c.Len() != 0 {
+			t.Fatal("expected a to be deleted")
+		}
+	})
+
+	t.Run("Capacity is respected", func(t *testing.T) {
+		c := NewWTinyLFU[int, int](50, 0)
+
+		evicted := 0
+		c.OnEvict(func(k int, v int, reason EvictReason) { evicted++ })
+
+		for i := 0; i < 1000; i++ {
+			c.Set(i, i)
+			c.Get(i % 10)
+		}
+
+		if c.Len() > 50 {
+			t.Fatalf("cache exceeded capacity: %d", c.Len())
+		}
+		if evicted != 1000-c.Len() {
+			t.Fatalf("expected %d evictions, got %d", 1000-c.Len(), evicted)
+		}
+	})
+
+	t.Run("Scan does not flush hot entries", func(t *testing.T) {
+		c := NewWTinyLFU[string, int](100, 0)
+		lru := NewLRU[string, int](100, 0)
+
+		for round := 0; round < 5; round++ {
+			for i := 0; i < 50; i++ {
+				key := fmt.Sprintf("hot-%d", i)
+				if _, ok := c.Get(key); !ok {
+					c.Set(key, i)
+				}
+				if _, ok := lru.Get(key); !ok {
+					lru.Set(key, i)
+				}
+			}
+		}
+
+		for i := 0; i < 1000; i++ {
+			key := fmt.Sprintf("scan-%d", i)
+			c.Set(key, i)
+			lru.Set(key, i)
+		}
+
+		tinyHot, lruHot := 0, 0
+		for i := 0; i < 50; i++ {
+			key := fmt.Sprintf("hot-%d", i)
+			if _, ok := c.Peek(key); ok {
+				tinyHot++
+			}
+			if _, ok := lru.Peek(key); ok {
+				lruHot++
+			}
+		}
+
+		if lruHot != 0 {
+			t.Fatalf("expected LRU to lose all hot entries, kept %d", lruHot)
+		}
+		if tinyHot < 45 {
+			t.Fatalf("expected W-TinyLFU to keep hot entries, kept %d of 50", tinyHot)
+		}
+	})
+
+	t.Run("Zipf hit ratio beats LRU", func(t *testing.T) {
+		keys := zipfWorkload(42, 100_000, 100_000, true)
+		tiny := hitRatio(NewWTinyLFU[uint64, uint64](1000, 0), keys)
+		lru := hitRatio(NewLRU[uint64, uint64](1000, 0), keys)
+
+		t.Logf("hit ratio: W-TinyLFU %.3f, LRU %.3f", tiny, lru)
+		if tiny <= lru {
+			t.Fatalf("expected W-TinyLFU hit ratio %.3f to beat LRU %.3f", tiny, lru)
+		}
+	})
+}
+
+// BenchmarkZipfHitRatio reports hit ratios of W-TinyLFU, LRU with TinyLFU admission and plain LRU on Zipf workloads
+// go test -bench=BenchmarkZipfHitRatio -run=^$ ./cache/
+func BenchmarkZipfHitRatio(b *testing.B) {
+	for _, scans := range []bool{false, true} {
+		keys := zipfWorkload(1, 1_000_000, 1_000_000, scans)
+
+		for _, capacity := range []int{1000, 10_000} {
+			policies := []struct {
+				name string
+				new  func() replayCache[uint64]
+			}{
+				{"LRU", func() replayCache[uint64] { return NewLRU[uint64, uint64](capacity, 0) }},
+				{"LRU+TinyLFU", func() replayCache[uint64] {
+					c := NewLRU[uint64, uint64](capacity, 0)
+					c.SetAdmission(NewTinyLFU[uint64](capacity))
+					return c
+				}},
+				{"WTinyLFU", func() replayCache[uint64] { return NewWTinyLFU[uint64, uint64](capacity, 0) }},
+			}
+
+			for _, p := range policies {
+				b.Run(fmt.Sprintf("%s/cap-%d/scans-%v", p.name, capacity, scans), func(b *testing.B) {
+					var ratio float64
+					for i := 0; i < b.N; i++ {
+						ratio = hitRatio(p.new(), keys)
+					}
+					b.ReportMetric(ratio*100, "hit%")
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"io"
+
+	base "go-helloworld/filter"
+)
+
+// Binary format, all numbers are little endian:
+//
+//	magic "BLMF" | version uint8 | hash id uint8 | reserved uint16 | m uint64 | k uint64 | bitset []uint64 | crc32 uint32
+//
+// crc32 (IEEE) covers everything before it.
+const (
+	binaryMagic      = "BLMF"
+	binaryVersion    = 1
+	binaryHeaderSize = 24
+	checksumSize     = 4
+)
+
+var (
+	ErrNotPortable  = errors.New("filter: hash function can not be serialized")
+	ErrIncompatible = errors.New("filter: filters have different size or hash function")
+)
+
+// hasherByID returns the hasher for a serialized filter, only hashers with fixed seeds can be restored
+func hasherByID(id HashID) (Hasher, error) {
+	switch id {
+	case HashFNV:
+		return FNVHasher{}, nil
+	case HashXX:
+		return DefaultXXHasher, nil
+	default:
+		return nil, ErrNotPortable
+	}
+}
+
+// MarshalBinary encodes the filter, filters with MaphashHasher or custom XXHasher seeds return ErrNotPortable
+func (bf *BloomFilter) MarshalBinary() ([]byte, error) {
+	h, err := hasherByID(bf.hasher.ID())
+	if err != nil || h != bf.hasher {
+		return nil, ErrNotPortable
+	}
+
+	buf := make([]byte, 0, binaryHeaderSize+8*len(bf.bitset)+checksumSize)
+	buf = append(buf, binaryMagic...)
+	buf = append(buf, binaryVersion, byte(bf.hasher.ID()), 0, 0)
+	buf = binary.LittleEndian.AppendUint64(buf, bf.m)
+	buf = binary.LittleEndian.AppendUint64(buf, bf.k)
+	for _, w := range bf.bitset {
+		buf = binary.LittleEndian.AppendUint64(buf, w)
+	}
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf)), nil
+}
+
+// UnmarshalBinary replaces the filter with the decoded one
+func (bf *BloomFilter) UnmarshalBinary(data []byte) error {
+	hasher, m, k, err := decodeHeader(data)
+	if err != nil {
+		return err
+	}
+
+	words := (m + 63) / 64
+	if uint64(len(data)) != binaryHeaderSize+8*words+checksumSize {
+		return base.ErrInvalidData
+	}
+
+	body := data[:len(data)-checksumSize]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(body):]) {
+		return base.ErrChecksum
+	}
+
+	bitset := make([]uint64, words)
+	for i := range bitset {
+		bitset[i] = binary.LittleEndian.Uint64(body[binaryHeaderSize+8*i:])
+	}
+
+	*bf = BloomFilter{m: m, k: k, bitset: bitset, hasher: hasher}
+	return nil
+}
+
+// decodeHeader validates the header and returns the filter parameters
+func decodeHeader(data []byte) (Hasher, uint64, uint64, error) {
+	if len(data) < binaryHeaderSize || string(data[:4]) != binaryMagic {
+		return nil, 0, 0, base.ErrInvalidData
+	}
+	if data[4] != binaryVersion {
+		return nil, 0, 0, base.ErrVersion
+	}
+
+	hasher, err := hasherByID(HashID(data[5]))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	m := binary.LittleEndian.Uint64(data[8:16])
+	k := binary.LittleEndian.Uint64(data[16:24])
+	if m == 0 || k == 0 || m > 1<<40 {
+		return nil, 0, 0, base.ErrInvalidData
+	}
+	return hasher, m, k, nil
+}
+
+// WriteTo writes the binary encoding of the filter to w
+func (bf *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	data, err := bf.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadFrom reads one filter from r, it does not read past the end of the filter
+func (bf *BloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, binaryHeaderSize)
+	n, err := io.ReadFull(r, header)
+	if err != nil {
+		return int64(n), err
+	}
+
+	_, m, _, err := decodeHeader(header)
+	if err != nil {
+		return int64(n), err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	rest, err := io.CopyN(&buf, r, int64(8*((m+63)/64)+checksumSize))
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return int64(n) + rest, err
+	}
+
+	return int64(n) + rest, bf.UnmarshalBinary(buf.Bytes())
+}
+
+// compatible reports whether the filters map items to the same bits
+func (bf *BloomFilter) compatible(other *BloomFilter) bool {
+	return bf.m == other.m && bf.k == other.k && bf.hasher == other.hasher
+}
+
+// Union adds all items of other to the filter, the result is the same as adding items of both filters to one
+func (bf *BloomFilter) Union(other *BloomFilter) error {
+	if !bf.compatible(other) {
+		return ErrIncompatible
+	}
+
+	for i, w := range other.bitset {
+		bf.bitset[i] |= w
+	}
+	return nil
+}
+
+// Intersect keeps only bits set in both filters. Every item added to both filters is still found,
+// but the false positive rate is higher than of a filter built from the intersection of items.
+func (bf *BloomFilter) Intersect(other *BloomFilter) error {
+	if !bf.compatible(other) {
+		return ErrIncompatible
+	}
+
+	for i, w := range other.bitset {
+		bf.bitset[i] &= w
+	}
+	return nil
+}
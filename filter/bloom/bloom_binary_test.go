@@ -0,0 +1,167 @@
+package filter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+
+	base "go-helloworld/filter"
+)
+
+func TestBloomFilterBinary(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		for _, h := range []Hasher{FNVHasher{}, DefaultXXHasher} {
+			src := NewBloomFilterWithFPR(1000, 0.01, WithHasher(h))
+			rnd := rand.New(rand.NewSource(1))
+			items := make([][]byte, 500)
+			for i := range items {
+				items[i] = randomItem(rnd, "in")
+				src.Add(items[i])
+			}
+
+			data, err := src.MarshalBinary()
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+
+			var dst BloomFilter
+			if err := dst.UnmarshalBinary(data); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if dst.m != src.m || dst.k != src.k || dst.hasher != src.hasher {
+				t.Fatalf("expected m=%d k=%d, got m=%d k=%d", src.m, src.k, dst.m, dst.k)
+			}
+			for _, item := range items {
+				if !dst.Contains(item) {
+					t.Fatalf("expected %s to be found after round trip", item)
+				}
+			}
+		}
+	})
+
+	t.Run("WriteTo and ReadFrom", func(t *testing.T) {
+		first := NewBloomFilterWithFPR(100, 0.01)
+		first.Add([]byte("apple"))
+		second := NewBloomFilterWithFPR(200, 0.001)
+		second.Add([]byte("banana"))
+
+		// filters are written one after another, so ReadFrom must stop at the end of the first one
+		var buf bytes.Buffer
+		n1, err := first.WriteTo(&buf)
+		if err != nil {
+			t.Fatalf("write first: %v", err)
+		}
+		if _, err := second.WriteTo(&buf); err != nil {
+			t.Fatalf("write second: %v", err)
+		}
+
+		var a, b BloomFilter
+		if n, err := a.ReadFrom(&buf); err != nil || n != n1 {
+			t.Fatalf("read first: %d bytes, %v", n, err)
+		}
+		if _, err := b.ReadFrom(&buf); err != nil {
+			t.Fatalf("read second: %v", err)
+		}
+		if !a.Contains([]byte("apple")) || !b.Contains([]byte("banana")) {
+			t.Fatal("expected items to be found after ReadFrom")
+		}
+		if _, err := b.ReadFrom(&buf); err != io.EOF {
+			t.Fatalf("expected EOF, got %v", err)
+		}
+	})
+
+	t.Run("Corrupted data", func(t *testing.T) {
+		bf := NewBloomFilterWithFPR(100, 0.01)
+		bf.Add([]byte("apple"))
+		data, _ := bf.MarshalBinary()
+
+		corrupt := func(i int, b byte) []byte {
+			c := bytes.Clone(data)
+			c[i] = b
+			return c
+		}
+
+		cases := map[string]struct {
+			data []byte
+			err  error
+		}{
+			"magic":     {data: corrupt(0, 'X'), err: base.ErrInvalidData},
+			"version":   {data: corrupt(4, 99), err: base.ErrVersion},
+			"hash id":   {data: corrupt(5, byte(HashMaphash)), err: ErrNotPortable},
+			"bitset":    {data: corrupt(binaryHeaderSize, data[binaryHeaderSize]^1), err: base.ErrChecksum},
+			"truncated": {data: data[:len(data)-1], err: base.ErrInvalidData},
+			"empty":     {data: nil, err: base.ErrInvalidData},
+		}
+		for name, tc := range cases {
+			var dst BloomFilter
+			if err := dst.UnmarshalBinary(tc.data); !errors.Is(err, tc.err) {
+				t.Fatalf("%s: expected %v, got %v", name, tc.err, err)
+			}
+		}
+
+		var dst BloomFilter
+		if _, err := dst.ReadFrom(bytes.NewReader(data[:len(data)-1])); err != io.ErrUnexpectedEOF {
+			t.Fatalf("expected unexpected EOF, got %v", err)
+		}
+	})
+
+	t.Run("Maphash is not portable", func(t *testing.T) {
+		bf := NewBloomFilterWithFPR(100, 0.01, WithHasher(NewMaphashHasher()))
+		if _, err := bf.MarshalBinary(); !errors.Is(err, ErrNotPortable) {
+			t.Fatalf("expected ErrNotPortable, got %v", err)
+		}
+
+		custom := NewBloomFilterWithFPR(100, 0.01, WithHasher(XXHasher{Seed1: 1, Seed2: 2}))
+		if _, err := custom.MarshalBinary(); !errors.Is(err, ErrNotPortable) {
+			t.Fatalf("expected ErrNotPortable for custom seeds, got %v", err)
+		}
+	})
+}
+
+func TestBloomFilterUnionIntersect(t *testing.T) {
+	a := NewBloomFilterWithFPR(1000, 0.01)
+	b := NewBloomFilterWithFPR(1000, 0.01)
+	a.Add([]byte("apple"))
+	a.Add([]byte("shared"))
+	b.Add([]byte("banana"))
+	b.Add([]byte("shared"))
+
+	union := NewBloomFilterWithFPR(1000, 0.01)
+	if err := union.Union(a); err != nil {
+		t.Fatalf("union: %v", err)
+	}
+	if err := union.Union(b); err != nil {
+		t.Fatalf("union: %v", err)
+	}
+	for _, item := range []string{"apple", "banana", "shared"} {
+		if !union.Contains([]byte(item)) {
+			t.Fatalf("expected union to contain %s", item)
+		}
+	}
+
+	if err := a.Intersect(b); err != nil {
+		t.Fatalf("intersect: %v", err)
+	}
+	if !a.Contains([]byte("shared")) {
+		t.Fatal("expected intersection to contain shared")
+	}
+	if a.Contains([]byte("apple")) || a.Contains([]byte("banana")) {
+		t.Fatal("expected intersection to drop items of one filter")
+	}
+
+	incompatible := []*BloomFilter{
+		NewBloomFilterWithFPR(2000, 0.01),
+		NewBloomFilter(1000, 10, 3),
+		NewBloomFilterWithFPR(1000, 0.01, WithHasher(DefaultXXHasher)),
+	}
+	for _, other := range incompatible {
+		if err := a.Union(other); !errors.Is(err, ErrIncompatible) {
+			t.Fatalf("expected ErrIncompatible for union, got %v", err)
+		}
+		if err := a.Intersect(other); !errors.Is(err, ErrIncompatible) {
+			t.Fatalf("expected ErrIncompatible for intersect, got %v", err)
+		}
+	}
+}
@@ -1,51 +1,83 @@
 package filter
 
 import (
-	"encoding/binary"
-	"hash/fnv"
+	"math"
+	"math/bits"
 )
 
 type BloomFilter struct {
-	m         uint64 // number of bytes in bitset
-	k         uint64 // numbed of hash-funcs
-	bitset    []uint64
-	hashFuncs []func([]byte) uint64
+	m      uint64 // number of bits in bitset
+	k      uint64 // numbed of hash-funcs
+	bitset []uint64
+	hasher Hasher
 }
 
-// NewBloomFilter created new Bloom filter
-// expectedItems — expected number of words
-func NewBloomFilter(expectedItems uint64, bitsPerItem uint64, k uint64) *BloomFilter {
-	m := expectedItems * bitsPerItem
+// Option configures the filter on creation
+type Option func(bf *BloomFilter)
 
-	wordCount := (m + 63) / 64
+// WithHasher sets the hash function, FNVHasher is used by default
+func WithHasher(h Hasher) Option {
+	return func(bf *BloomFilter) {
+		bf.hasher = h
+	}
+}
 
-	bitset := make([]uint64, wordCount)
+// NewBloomFilter created new Bloom filter
+// expectedItems — expected number of words
+func NewBloomFilter(expectedItems uint64, bitsPerItem uint64, k uint64, opts ...Option) *BloomFilter {
+	return newBloomFilter(expectedItems*bitsPerItem, k, opts...)
+}
 
-	hashFuncs := make([]func([]byte) uint64, k)
+// NewBloomFilterWithFPR creates Bloom filter for n items with false positive rate p,
+// it picks the optimal number of bits m = -n*ln(p)/ln(2)^2 and hash-funcs k = m/n*ln(2).
+// p must be in (0, 1).
+func NewBloomFilterWithFPR(n uint64, p float64, opts ...Option) *BloomFilter {
+	m, k := optimalParams(n, p)
+	return newBloomFilter(m, k, opts...)
+}
 
-	// Initiate every hash-funcs based on FNV-1a and seed.
-	for seed := uint64(0); seed < k; seed++ {
-		hashFuncs[seed] = func(data []byte) uint64 {
-			h := fnv.New64a() // new 64-bit FNV-1a hasher [web:11]
+// optimalParams returns number of bits m and hash-funcs k for n items with false positive rate p
+func optimalParams(n uint64, p float64) (uint64, uint64) {
+	if p <= 0 || p >= 1 {
+		panic("filter: false positive rate must be in (0, 1)")
+	}
+	n = max(n, 1)
 
-			// Write seed as 8 bytes into hasher
-			seedBytes := make([]byte, 8)
-			binary.LittleEndian.PutUint64(seedBytes, seed)
-			h.Write(seedBytes)
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint64(max(math.Round(float64(m)/float64(n)*math.Ln2), 1))
+	return m, k
+}
 
-			h.Write(data)
+func newBloomFilter(m uint64, k uint64, opts ...Option) *BloomFilter {
+	wordCount := (m + 63) / 64
 
-			// Return 64-bit hash result
-			return h.Sum64()
-		}
+	bf := &BloomFilter{
+		m:      m,
+		k:      k,
+		bitset: make([]uint64, wordCount),
+		hasher: FNVHasher{},
 	}
+	for _, opt := range opts {
+		opt(bf)
+	}
+	return bf
+}
 
-	return &BloomFilter{
-		m:         m,
-		k:         k,
-		bitset:    bitset,
-		hashFuncs: hashFuncs,
+// optionsHasher returns the hasher configured by opts, options configure BloomFilter,
+// so other filters apply them to an empty one
+func optionsHasher(opts []Option) Hasher {
+	bf := BloomFilter{hasher: FNVHasher{}}
+	for _, opt := range opts {
+		opt(&bf)
 	}
+	return bf.hasher
+}
+
+// location returns hash value of the i-th hash-func.
+// All k values are derived from one 128-bit hash with Kirsch-Mitzenmacher double hashing h1 + i*h2,
+// which keeps the false positive rate of k independent hashes. h2 is odd, so the values never collapse to h1.
+func location(h1, h2, i uint64) uint64 {
+	return h1 + i*(h2|1)
 }
 
 // bitLocation define word index in bf.bitset and index of a byte in a word
@@ -79,23 +111,20 @@ func (bf *BloomFilter) isBitSet(hashValue uint64) bool {
 	return (bf.bitset[wordIndex] & mask) != 0
 }
 
-// Add adds element to filter
-func (bf *BloomFilter) Add(item []byte) {
+// Add adds element to filter, a Bloom filter never gets full, so it always returns true
+func (bf *BloomFilter) Add(item []byte) bool {
+	h1, h2 := bf.hasher.Sum128(item)
 	for i := uint64(0); i < bf.k; i++ {
-		h := bf.hashFuncs[i]
-		hashValue := h(item)
-
-		bf.setBit(hashValue)
+		bf.setBit(location(h1, h2, i))
 	}
+	return true
 }
 
 // Contains checks is element "probably exists", or "certainly does not exist" in the filter
 func (bf *BloomFilter) Contains(item []byte) bool {
+	h1, h2 := bf.hasher.Sum128(item)
 	for i := uint64(0); i < bf.k; i++ {
-		h := bf.hashFuncs[i]
-		hashValue := h(item)
-
-		if !bf.isBitSet(hashValue) {
+		if !bf.isBitSet(location(h1, h2, i)) {
 			// element certainly does not exist
 			return false
 		}
@@ -104,3 +133,40 @@ func (bf *BloomFilter) Contains(item []byte) bool {
 	// Element probably exists
 	return true
 }
+
+// Reset clears all bits, so the filter can be reused without allocating a new bitset
+func (bf *BloomFilter) Reset() {
+	clear(bf.bitset)
+}
+
+// SizeBits returns size of the bitset in bits
+func (bf *BloomFilter) SizeBits() uint64 {
+	return bf.m
+}
+
+// bitCount returns number of bits set to 1
+func (bf *BloomFilter) bitCount() uint64 {
+	var n int
+	for _, w := range bf.bitset {
+		n += bits.OnesCount64(w)
+	}
+	return uint64(n)
+}
+
+// FillRatio returns share of bits set to 1
+func (bf *BloomFilter) FillRatio() float64 {
+	return float64(bf.bitCount()) / float64(bf.m)
+}
+
+// EstimatedFPR returns current false positive rate, which is the chance that all k bits of a new item are already set
+func (bf *BloomFilter) EstimatedFPR() float64 {
+	return math.Pow(bf.FillRatio(), float64(bf.k))
+}
+
+// ApproxCount estimates number of added distinct items from the number of set bits with
+// Swamidass & Baldi formula n = -m/k * ln(1 - X/m). A saturated filter is counted as if one bit is still unset.
+func (bf *BloomFilter) ApproxCount() uint64 {
+	x := float64(min(bf.bitCount(), bf.m-1))
+	m := float64(bf.m)
+	return uint64(math.Round(-m / float64(bf.k) * math.Log(1-x/m)))
+}
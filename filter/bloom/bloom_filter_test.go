@@ -2,6 +2,8 @@ package filter
 
 import (
 	"fmt"
+	"math"
+	"math/rand"
 	"testing"
 )
 
@@ -16,3 +18,64 @@ func TestBloomFilter(t *testing.T) {
 	fmt.Println("Contains 'apple':", bf.Contains(item1))
 	fmt.Println("Contains 'banana':", bf.Contains(item2))
 }
+
+func TestNewBloomFilterWithFPR(t *testing.T) {
+	cases := []struct {
+		n uint64
+		p float64
+	}{
+		{n: 1000, p: 0.1},
+		{n: 10000, p: 0.01},
+		{n: 20000, p: 0.001},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("n=%d p=%g", tc.n, tc.p), func(t *testing.T) {
+			bf := NewBloomFilterWithFPR(tc.n, tc.p)
+			rnd := rand.New(rand.NewSource(int64(tc.n)))
+
+			for i := uint64(0); i < tc.n; i++ {
+				bf.Add(randomItem(rnd, "in"))
+			}
+
+			// every added item must be found, so check false positives on items which were never added
+			falsePositives := 0
+			probes := int(50 / tc.p)
+			for i := 0; i < probes; i++ {
+				if bf.Contains(randomItem(rnd, "out")) {
+					falsePositives++
+				}
+			}
+
+			measured := float64(falsePositives) / float64(probes)
+			if measured > tc.p*1.5 {
+				t.Fatalf("measured FPR %.5f is too far above target %.5f", measured, tc.p)
+			}
+			if est := bf.EstimatedFPR(); math.Abs(est-tc.p) > tc.p*0.3 {
+				t.Fatalf("estimated FPR %.5f is too far from target %.5f", est, tc.p)
+			}
+			if fill := bf.FillRatio(); math.Abs(fill-0.5) > 0.05 {
+				t.Fatalf("expected optimal filter to be about half full, got %.3f", fill)
+			}
+			if count := bf.ApproxCount(); math.Abs(float64(count)-float64(tc.n)) > float64(tc.n)*0.05 {
+				t.Fatalf("approximate count %d is too far from %d", count, tc.n)
+			}
+		})
+	}
+}
+
+func TestBloomFilterEmpty(t *testing.T) {
+	bf := NewBloomFilterWithFPR(100, 0.01)
+
+	if bf.FillRatio() != 0 || bf.EstimatedFPR() != 0 || bf.ApproxCount() != 0 {
+		t.Fatalf("expected empty filter, got fill %.3f, fpr %.3f, count %d", bf.FillRatio(), bf.EstimatedFPR(), bf.ApproxCount())
+	}
+	if bf.Contains([]byte("apple")) {
+		t.Fatal("expected empty filter to contain nothing")
+	}
+}
+
+// randomItem returns a random item with prefix, so added and probed items never collide
+func randomItem(rnd *rand.Rand, prefix string) []byte {
+	return fmt.Appendf(nil, "%s-%016x", prefix, rnd.Uint64())
+}
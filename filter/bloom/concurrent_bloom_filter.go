@@ -0,0 +1,84 @@
+package filter
+
+import "sync/atomic"
+
+// ConcurrentBloomFilter is a thread-safe Bloom filter without locks.
+// Bits are set with atomic OR on whole words, so concurrent Add calls never lose each other's bits,
+// and Contains reads words with atomic loads.
+type ConcurrentBloomFilter struct {
+	m      uint64 // number of bits in bitset
+	k      uint64 // number of hash-funcs
+	bitset []atomic.Uint64
+	hasher Hasher
+}
+
+// NewConcurrentBloomFilter creates concurrent filter with expectedItems*bitsPerItem bits and k hash-funcs
+func NewConcurrentBloomFilter(expectedItems uint64, bitsPerItem uint64, k uint64, opts ...Option) *ConcurrentBloomFilter {
+	return newConcurrentBloomFilter(expectedItems*bitsPerItem, k, opts...)
+}
+
+// NewConcurrentBloomFilterWithFPR creates concurrent filter for n items with false positive rate p
+func NewConcurrentBloomFilterWithFPR(n uint64, p float64, opts ...Option) *ConcurrentBloomFilter {
+	m, k := optimalParams(n, p)
+	return newConcurrentBloomFilter(m, k, opts...)
+}
+
+func newConcurrentBloomFilter(m uint64, k uint64, opts ...Option) *ConcurrentBloomFilter {
+	return &ConcurrentBloomFilter{
+		m:      m,
+		k:      k,
+		bitset: make([]atomic.Uint64, (m+63)/64),
+		hasher: optionsHasher(opts),
+	}
+}
+
+// Add adds element to filter, it is safe to call concurrently with Add and Contains. It always returns true.
+func (cf *ConcurrentBloomFilter) Add(item []byte) bool {
+	h1, h2 := cf.hasher.Sum128(item)
+	for i := uint64(0); i < cf.k; i++ {
+		pos := location(h1, h2, i) % cf.m
+		word := &cf.bitset[pos/64]
+		mask := uint64(1) << (pos % 64)
+
+		// most bits of a loaded filter are already set, a plain load avoids a write to a shared cache line
+		if word.Load()&mask == 0 {
+			word.Or(mask)
+		}
+	}
+	return true
+}
+
+// Contains checks is element "probably exists", or "certainly does not exist" in the filter.
+// An item added concurrently may be not found until its Add returns.
+func (cf *ConcurrentBloomFilter) Contains(item []byte) bool {
+	h1, h2 := cf.hasher.Sum128(item)
+	for i := uint64(0); i < cf.k; i++ {
+		pos := location(h1, h2, i) % cf.m
+		if cf.bitset[pos/64].Load()&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SizeBits returns size of the bitset in bits
+func (cf *ConcurrentBloomFilter) SizeBits() uint64 {
+	return cf.m
+}
+
+// Reset clears all bits, items added concurrently with Reset may be partially kept
+func (cf *ConcurrentBloomFilter) Reset() {
+	for i := range cf.bitset {
+		cf.bitset[i].Store(0)
+	}
+}
+
+// ToBloom returns a copy as the plain Bloom filter, e.g. to serialize it.
+// Items added concurrently with ToBloom may be partially copied.
+func (cf *ConcurrentBloomFilter) ToBloom() *BloomFilter {
+	bf := newBloomFilter(cf.m, cf.k, WithHasher(cf.hasher))
+	for i := range cf.bitset {
+		bf.bitset[i] = cf.bitset[i].Load()
+	}
+	return bf
+}
@@ -0,0 +1,157 @@
+package filter
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConcurrentBloomFilter(t *testing.T) {
+	t.Run("Concurrent Add does not lose bits", func(t *testing.T) {
+		const workers, perWorker = 8, 2000
+		cf := NewConcurrentBloomFilterWithFPR(workers*perWorker, 0.01)
+
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					item := fmt.Appendf(nil, "worker-%d-%d", w, i)
+					cf.Add(item)
+					if !cf.Contains(item) {
+						t.Errorf("expected %s to be found right after Add", item)
+						return
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		for w := 0; w < workers; w++ {
+			for i := 0; i < perWorker; i++ {
+				if item := fmt.Appendf(nil, "worker-%d-%d", w, i); !cf.Contains(item) {
+					t.Fatalf("expected %s to be found", item)
+				}
+			}
+		}
+	})
+
+	t.Run("Readers run with writers", func(t *testing.T) {
+		cf := NewConcurrentBloomFilterWithFPR(10000, 0.01)
+		cf.Add([]byte("always"))
+
+		var wg sync.WaitGroup
+		var stop atomic.Bool
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for !stop.Load() {
+					if !cf.Contains([]byte("always")) {
+						t.Error("expected item added before readers to be found")
+						return
+					}
+				}
+			}()
+		}
+
+		rnd := rand.New(rand.NewSource(1))
+		for i := 0; i < 10000; i++ {
+			cf.Add(randomItem(rnd, "in"))
+		}
+		stop.Store(true)
+		wg.Wait()
+	})
+
+	t.Run("ToBloom", func(t *testing.T) {
+		cf := NewConcurrentBloomFilterWithFPR(1000, 0.01, WithHasher(DefaultXXHasher))
+		for i := 0; i < 500; i++ {
+			cf.Add(fmt.Appendf(nil, "item-%d", i))
+		}
+
+		bf := cf.ToBloom()
+		for i := 0; i < 1000; i++ {
+			item := fmt.Appendf(nil, "item-%d", i)
+			if bf.Contains(item) != cf.Contains(item) {
+				t.Fatalf("expected the same answer for %s", item)
+			}
+		}
+		if _, err := bf.MarshalBinary(); err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+
+		cf.Reset()
+		if cf.Contains([]byte("item-1")) {
+			t.Fatal("expected Reset to clear the filter")
+		}
+	})
+}
+
+type bloomSet interface {
+	Add(item []byte) bool
+	Contains(item []byte) bool
+}
+
+// lockedBloomFilter is the plain filter behind a mutex, the alternative to compare with
+type lockedBloomFilter struct {
+	mu sync.RWMutex
+	bf *BloomFilter
+}
+
+func (l *lockedBloomFilter) Add(item []byte) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.bf.Add(item)
+}
+
+func (l *lockedBloomFilter) Contains(item []byte) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.bf.Contains(item)
+}
+
+func BenchmarkParallelBloomFilter(b *testing.B) {
+	items := make([][]byte, 4096)
+	rnd := rand.New(rand.NewSource(1))
+	for i := range items {
+		items[i] = randomItem(rnd, "item")
+	}
+
+	filters := []struct {
+		name string
+		new  func() bloomSet
+	}{
+		{"rwmutex", func() bloomSet {
+			return &lockedBloomFilter{bf: NewBloomFilterWithFPR(100000, 0.01, WithHasher(DefaultXXHasher))}
+		}},
+		{"atomic", func() bloomSet {
+			return NewConcurrentBloomFilterWithFPR(100000, 0.01, WithHasher(DefaultXXHasher))
+		}},
+	}
+
+	for _, f := range filters {
+		for _, goroutines := range []int{1, 4, 16} {
+			// every 10th operation is Add, the rest is Contains, like a dedupe filter of a consumer
+			b.Run(fmt.Sprintf("%s/goroutines-per-cpu=%d", f.name, goroutines), func(b *testing.B) {
+				filter := f.new()
+				b.SetParallelism(goroutines)
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					i := rand.Int()
+					for pb.Next() {
+						item := items[i%len(items)]
+						if i%10 == 0 {
+							filter.Add(item)
+						} else {
+							filter.Contains(item)
+						}
+						i++
+					}
+				})
+			})
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package filter
+
+const (
+	counterBits    = 4
+	counterMax     = 1<<counterBits - 1
+	countersInWord = 64 / counterBits
+)
+
+// CountingBloomFilter is a Bloom filter with 4-bit counters instead of bits, so items can be removed.
+// A counter which reaches 15 saturates: it is never changed again, as after that it is not known
+// how many items use it. Saturated counters are reported with Overflows.
+type CountingBloomFilter struct {
+	m         uint64 // number of counters
+	k         uint64 // number of hash-funcs
+	counters  []uint64
+	hasher    Hasher
+	overflows uint64
+}
+
+// NewCountingBloomFilter creates counting filter with expectedItems*bitsPerItem counters and k hash-funcs
+func NewCountingBloomFilter(expectedItems uint64, bitsPerItem uint64, k uint64, opts ...Option) *CountingBloomFilter {
+	return newCountingBloomFilter(expectedItems*bitsPerItem, k, opts...)
+}
+
+// NewCountingBloomFilterWithFPR creates counting filter for n items with false positive rate p
+func NewCountingBloomFilterWithFPR(n uint64, p float64, opts ...Option) *CountingBloomFilter {
+	m, k := optimalParams(n, p)
+	return newCountingBloomFilter(m, k, opts...)
+}
+
+func newCountingBloomFilter(m uint64, k uint64, opts ...Option) *CountingBloomFilter {
+	return &CountingBloomFilter{
+		m:        m,
+		k:        k,
+		counters: make([]uint64, (m+countersInWord-1)/countersInWord),
+		hasher:   optionsHasher(opts),
+	}
+}
+
+// counterLocation returns word index in cf.counters and shift of the counter in the word
+func (cf *CountingBloomFilter) counterLocation(hashValue uint64) (uint64, uint64) {
+	pos := hashValue % cf.m
+	return pos / countersInWord, (pos % countersInWord) * counterBits
+}
+
+func (cf *CountingBloomFilter) counter(hashValue uint64) uint64 {
+	wordIndex, shift := cf.counterLocation(hashValue)
+	return cf.counters[wordIndex] >> shift & counterMax
+}
+
+// Add adds element to filter, it always returns true, a saturated counter is reported with Overflows
+func (cf *CountingBloomFilter) Add(item []byte) bool {
+	h1, h2 := cf.hasher.Sum128(item)
+	for i := uint64(0); i < cf.k; i++ {
+		wordIndex, shift := cf.counterLocation(location(h1, h2, i))
+
+		c := cf.counters[wordIndex] >> shift & counterMax
+		switch {
+		case c == counterMax:
+			// already saturated
+		case c == counterMax-1:
+			cf.overflows++
+			fallthrough
+		default:
+			cf.counters[wordIndex] += 1 << shift
+		}
+	}
+	return true
+}
+
+// Remove removes element which was added before. If the element is certainly not in the filter, it returns
+// false and changes nothing, as decrementing counters of other items would bring false negatives.
+// Removing an element which was never added but is a false positive still corrupts the filter.
+func (cf *CountingBloomFilter) Remove(item []byte) bool {
+	if !cf.Contains(item) {
+		return false
+	}
+
+	h1, h2 := cf.hasher.Sum128(item)
+	for i := uint64(0); i < cf.k; i++ {
+		wordIndex, shift := cf.counterLocation(location(h1, h2, i))
+
+		// the same counter can be hit twice by one item, so it is checked again on every step
+		if c := cf.counters[wordIndex] >> shift & counterMax; c > 0 && c < counterMax {
+			cf.counters[wordIndex] -= 1 << shift
+		}
+	}
+	return true
+}
+
+// Contains checks is element "probably exists", or "certainly does not exist" in the filter
+func (cf *CountingBloomFilter) Contains(item []byte) bool {
+	h1, h2 := cf.hasher.Sum128(item)
+	for i := uint64(0); i < cf.k; i++ {
+		if cf.counter(location(h1, h2, i)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// SizeBits returns size of the counters in bits
+func (cf *CountingBloomFilter) SizeBits() uint64 {
+	return cf.m * counterBits
+}
+
+// Overflows returns number of counters which got saturated
+func (cf *CountingBloomFilter) Overflows() uint64 {
+	return cf.overflows
+}
+
+// ToBloom returns the plain Bloom filter with bits set for non-zero counters.
+// It contains the same items and is 4 times smaller, so it suits read-only distribution.
+func (cf *CountingBloomFilter) ToBloom() *BloomFilter {
+	bf := newBloomFilter(cf.m, cf.k, WithHasher(cf.hasher))
+	for pos := uint64(0); pos < cf.m; pos++ {
+		if cf.counters[pos/countersInWord]>>((pos%countersInWord)*counterBits)&counterMax != 0 {
+			bf.bitset[pos/64] |= 1 << (pos % 64)
+		}
+	}
+	return bf
+}
+
+// Reset clears all counters
+func (cf *CountingBloomFilter) Reset() {
+	clear(cf.counters)
+	cf.overflows = 0
+}
@@ -0,0 +1,123 @@
+package filter
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestCountingBloomFilter(t *testing.T) {
+	t.Run("Add and Remove", func(t *testing.T) {
+		cf := NewCountingBloomFilterWithFPR(1000, 0.01)
+		rnd := rand.New(rand.NewSource(1))
+
+		items := make([][]byte, 1000)
+		for i := range items {
+			items[i] = randomItem(rnd, "in")
+			cf.Add(items[i])
+		}
+
+		// remove every second item, the rest must still be found
+		for i := 0; i < len(items); i += 2 {
+			if !cf.Remove(items[i]) {
+				t.Fatalf("remove failed for %s", items[i])
+			}
+		}
+		for i := 1; i < len(items); i += 2 {
+			if !cf.Contains(items[i]) {
+				t.Fatalf("expected %s to be found after removing others", items[i])
+			}
+		}
+
+		removedFound := 0
+		for i := 0; i < len(items); i += 2 {
+			if cf.Contains(items[i]) {
+				removedFound++
+			}
+		}
+		if removedFound > len(items)/2/20 {
+			t.Fatalf("expected most removed items to be gone, %d are still found", removedFound)
+		}
+		if cf.Overflows() != 0 {
+			t.Fatalf("expected no overflows, got %d", cf.Overflows())
+		}
+	})
+
+	t.Run("Remove of absent item", func(t *testing.T) {
+		cf := NewCountingBloomFilterWithFPR(100, 0.01)
+		cf.Add([]byte("apple"))
+
+		if cf.Remove([]byte("banana")) {
+			t.Fatal("expected remove of absent item to fail")
+		}
+		if !cf.Contains([]byte("apple")) {
+			t.Fatal("expected apple to stay")
+		}
+	})
+
+	t.Run("Duplicates need the same number of removals", func(t *testing.T) {
+		cf := NewCountingBloomFilterWithFPR(100, 0.01)
+		item := []byte("apple")
+		cf.Add(item)
+		cf.Add(item)
+
+		cf.Remove(item)
+		if !cf.Contains(item) {
+			t.Fatal("expected apple to stay after the first removal")
+		}
+		cf.Remove(item)
+		if cf.Contains(item) {
+			t.Fatal("expected apple to be removed")
+		}
+	})
+
+	t.Run("Counters saturate", func(t *testing.T) {
+		cf := NewCountingBloomFilter(100, 10, 3)
+		item := []byte("hot")
+		for i := 0; i < 20; i++ {
+			cf.Add(item)
+		}
+		if cf.Overflows() == 0 {
+			t.Fatal("expected overflows to be reported")
+		}
+
+		// saturated counters are never decremented, so the item can not be removed by mistake
+		for i := 0; i < 20; i++ {
+			cf.Remove(item)
+		}
+		if !cf.Contains(item) {
+			t.Fatal("expected saturated item to stay")
+		}
+
+		cf.Reset()
+		if cf.Contains(item) || cf.Overflows() != 0 {
+			t.Fatal("expected Reset to clear the filter")
+		}
+	})
+}
+
+func TestCountingBloomFilterToBloom(t *testing.T) {
+	cf := NewCountingBloomFilterWithFPR(1000, 0.01, WithHasher(DefaultXXHasher))
+	for i := 0; i < 500; i++ {
+		cf.Add(fmt.Appendf(nil, "item-%d", i))
+	}
+	for i := 0; i < 100; i++ {
+		cf.Remove(fmt.Appendf(nil, "item-%d", i))
+	}
+
+	data, err := cf.ToBloom().MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var bf BloomFilter
+	if err := bf.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		item := fmt.Appendf(nil, "item-%d", i)
+		if bf.Contains(item) != cf.Contains(item) {
+			t.Fatalf("expected the same answer for %s", item)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package filter
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math/bits"
+)
+
+// HashID identifies the hash function, it is stored with a serialized filter
+type HashID uint8
+
+const (
+	HashFNV     HashID = iota + 1 // FNV-1a 128
+	HashXX                        // XXH64 with two seeds
+	HashMaphash                   // hash/maphash with two random seeds, valid only within one process
+)
+
+// Hasher computes a 128-bit hash of data as two 64-bit halves.
+// The filter derives all k bit positions from these halves, so it hashes every item once.
+// Hashers are compared with == to check that two filters are compatible, so they must be comparable.
+type Hasher interface {
+	ID() HashID
+	Sum128(data []byte) (uint64, uint64)
+}
+
+// FNVHasher is FNV-1a 128, the 128-bit multiplication is done inline, so unlike hash/fnv it never allocates
+type FNVHasher struct{}
+
+const (
+	fnv128OffsetHi   = 0x6c62272e07bb0142
+	fnv128OffsetLo   = 0x62b821756295c58d
+	fnv128PrimeLo    = 0x13b // FNV-128 prime is 2^88 + 0x13b
+	fnv128PrimeShift = 24    // 2^88 is 2^24 in the high word
+)
+
+func (FNVHasher) ID() HashID {
+	return HashFNV
+}
+
+func (FNVHasher) Sum128(data []byte) (uint64, uint64) {
+	hi, lo := uint64(fnv128OffsetHi), uint64(fnv128OffsetLo)
+	for _, c := range data {
+		lo ^= uint64(c)
+
+		// (hi*2^64 + lo) * (2^88 + 0x13b) mod 2^128
+		h, l := bits.Mul64(lo, fnv128PrimeLo)
+		hi = h + hi*fnv128PrimeLo + lo<<fnv128PrimeShift
+		lo = l
+	}
+	return hi, lo
+}
+
+// XXHasher runs XXH64 with two different seeds, it is the fastest of the hashers on long items
+type XXHasher struct {
+	Seed1, Seed2 uint64
+}
+
+// DefaultXXHasher uses fixed seeds, so filters built in different processes are compatible
+var DefaultXXHasher = XXHasher{Seed1: 0, Seed2: 0x9e3779b97f4a7c15}
+
+func (XXHasher) ID() HashID {
+	return HashXX
+}
+
+func (x XXHasher) Sum128(data []byte) (uint64, uint64) {
+	return xxh64(data, x.Seed1), xxh64(data, x.Seed2)
+}
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// xxh64 is the XXH64 hash of data
+func xxh64(data []byte, seed uint64) uint64 {
+	n := len(data)
+
+	var h uint64
+	if n >= 32 {
+		v1 := seed + xxPrime1 + xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+		for len(data) >= 32 {
+			v1 = xxRound(v1, binary.LittleEndian.Uint64(data[0:8]))
+			v2 = xxRound(v2, binary.LittleEndian.Uint64(data[8:16]))
+			v3 = xxRound(v3, binary.LittleEndian.Uint64(data[16:24]))
+			v4 = xxRound(v4, binary.LittleEndian.Uint64(data[24:32]))
+			data = data[32:]
+		}
+
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = seed + xxPrime5
+	}
+
+	h += uint64(n)
+
+	for ; len(data) >= 8; data = data[8:] {
+		h ^= xxRound(0, binary.LittleEndian.Uint64(data))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(data) >= 4 {
+		h ^= uint64(binary.LittleEndian.Uint32(data)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		data = data[4:]
+	}
+	for _, c := range data {
+		h ^= uint64(c) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	// avalanche
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+// MaphashHasher uses hash/maphash, which is AES-based on most CPUs.
+// Seeds are random, so a filter with this hasher can not be shared with other processes.
+type MaphashHasher struct {
+	seed1, seed2 maphash.Seed
+}
+
+func NewMaphashHasher() MaphashHasher {
+	return MaphashHasher{seed1: maphash.MakeSeed(), seed2: maphash.MakeSeed()}
+}
+
+func (MaphashHasher) ID() HashID {
+	return HashMaphash
+}
+
+func (h MaphashHasher) Sum128(data []byte) (uint64, uint64) {
+	return maphash.Bytes(h.seed1, data), maphash.Bytes(h.seed2, data)
+}
@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"testing"
+)
+
+func TestFNVHasher(t *testing.T) {
+	for _, s := range []string{"", "a", "apple", "the quick brown fox jumps over the lazy dog"} {
+		h := fnv.New128a()
+		h.Write([]byte(s))
+		sum := h.Sum(nil)
+
+		hi, lo := FNVHasher{}.Sum128([]byte(s))
+		if hi != binary.BigEndian.Uint64(sum[:8]) || lo != binary.BigEndian.Uint64(sum[8:]) {
+			t.Fatalf("%q: expected %x, got %016x%016x", s, sum, hi, lo)
+		}
+	}
+}
+
+func TestXXH64(t *testing.T) {
+	cases := []struct {
+		data string
+		seed uint64
+		want uint64
+	}{
+		{data: "", seed: 0, want: 0xef46db3751d8e999},
+		{data: "a", seed: 0, want: 0xd24ec4f1a98c6e5b},
+		{data: "abc", seed: 0, want: 0x44bc2cf5ad770999},
+		{data: "Nobody inspects the spammish repetition", seed: 0, want: 0xfbcea83c8a378bf1},
+	}
+
+	for _, tc := range cases {
+		if got := xxh64([]byte(tc.data), tc.seed); got != tc.want {
+			t.Fatalf("%q: expected %x, got %x", tc.data, tc.want, got)
+		}
+	}
+}
+
+func TestBloomFilterHashers(t *testing.T) {
+	hashers := []Hasher{FNVHasher{}, DefaultXXHasher, NewMaphashHasher()}
+
+	for _, h := range hashers {
+		t.Run(fmt.Sprintf("hash-%d", h.ID()), func(t *testing.T) {
+			const n, p = 10000, 0.01
+			bf := NewBloomFilterWithFPR(n, p, WithHasher(h))
+			rnd := rand.New(rand.NewSource(1))
+
+			for i := 0; i < n; i++ {
+				item := randomItem(rnd, "in")
+				bf.Add(item)
+				if !bf.Contains(item) {
+					t.Fatalf("expected %s to be found", item)
+				}
+			}
+
+			falsePositives := 0
+			const probes = 100000
+			for i := 0; i < probes; i++ {
+				if bf.Contains(randomItem(rnd, "out")) {
+					falsePositives++
+				}
+			}
+			if measured := float64(falsePositives) / probes; measured > p*1.5 {
+				t.Fatalf("measured FPR %.5f is too far above target %.5f", measured, p)
+			}
+		})
+	}
+}
+
+func TestBloomFilterDoesNotAllocate(t *testing.T) {
+	hashers := []Hasher{FNVHasher{}, DefaultXXHasher, NewMaphashHasher()}
+	item := []byte("some-kafka-message-key")
+
+	for _, h := range hashers {
+		bf := NewBloomFilterWithFPR(1000, 0.01, WithHasher(h))
+		allocs := testing.AllocsPerRun(100, func() {
+			bf.Add(item)
+			bf.Contains(item)
+		})
+		if allocs != 0 {
+			t.Fatalf("hash %d: expected no allocations, got %.1f", h.ID(), allocs)
+		}
+	}
+}
+
+// legacyHashFuncs is the old hashing with k FNV-1a closures and a seed buffer per call, kept to compare with
+func legacyHashFuncs(k uint64) []func([]byte) uint64 {
+	hashFuncs := make([]func([]byte) uint64, k)
+	for seed := uint64(0); seed < k; seed++ {
+		hashFuncs[seed] = func(data []byte) uint64 {
+			h := fnv.New64a()
+			seedBytes := make([]byte, 8)
+			binary.LittleEndian.PutUint64(seedBytes, seed)
+			h.Write(seedBytes)
+			h.Write(data)
+			return h.Sum64()
+		}
+	}
+	return hashFuncs
+}
+
+func BenchmarkBloomFilter(b *testing.B) {
+	items := make([][]byte, 1024)
+	rnd := rand.New(rand.NewSource(1))
+	for i := range items {
+		items[i] = randomItem(rnd, "item")
+	}
+
+	b.Run("legacy-k-fnv", func(b *testing.B) {
+		bf := NewBloomFilterWithFPR(100000, 0.01)
+		hashFuncs := legacyHashFuncs(bf.k)
+		b.ReportAllocs()
+		for i := 0; b.Loop(); i++ {
+			for _, h := range hashFuncs {
+				bf.setBit(h(items[i%len(items)]))
+			}
+		}
+	})
+
+	hashers := map[string]Hasher{
+		"fnv128a": FNVHasher{},
+		"xxhash":  DefaultXXHasher,
+		"maphash": NewMaphashHasher(),
+	}
+	for name, h := range hashers {
+		b.Run("add-"+name, func(b *testing.B) {
+			bf := NewBloomFilterWithFPR(100000, 0.01, WithHasher(h))
+			b.ReportAllocs()
+			for i := 0; b.Loop(); i++ {
+				bf.Add(items[i%len(items)])
+			}
+		})
+
+		b.Run("contains-"+name, func(b *testing.B) {
+			bf := NewBloomFilterWithFPR(100000, 0.01, WithHasher(h))
+			for _, item := range items[:len(items)/2] {
+				bf.Add(item)
+			}
+			b.ReportAllocs()
+			for i := 0; b.Loop(); i++ {
+				bf.Contains(items[i%len(items)])
+			}
+		})
+	}
+}
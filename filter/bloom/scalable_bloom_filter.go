@@ -0,0 +1,257 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+
+	base "go-helloworld/filter"
+)
+
+const (
+	scalableGrowth = 2   // every new slice holds twice as many items as the previous one
+	scalableRatio  = 0.8 // every new slice has 0.8 of the previous slice false positive rate
+)
+
+// ScalableBloomFilter keeps the false positive rate bounded when the number of items is not known in advance.
+// Items go to the last slice. When the slice is full a new one is added with twice the capacity and a tighter
+// false positive rate p0*r^i, so the overall rate 1 - Π(1 - p0*r^i) stays below p0/(1-r) = p.
+type ScalableBloomFilter struct {
+	p       float64 // target overall false positive rate
+	initial uint64  // capacity of the first slice
+	count   uint64  // number of items in the last slice
+	slices  []*BloomFilter
+	opts    []Option
+}
+
+// NewScalableBloomFilter creates scalable filter which starts with room for initialItems and grows as needed,
+// p is the upper bound of the false positive rate. p must be in (0, 1).
+func NewScalableBloomFilter(initialItems uint64, p float64, opts ...Option) *ScalableBloomFilter {
+	sf := &ScalableBloomFilter{
+		p:       p,
+		initial: max(initialItems, 1),
+		opts:    opts,
+	}
+	sf.grow()
+	return sf
+}
+
+// sliceParams returns capacity and false positive rate of the i-th slice
+func (sf *ScalableBloomFilter) sliceParams(i int) (uint64, float64) {
+	capacity := sf.initial * uint64(math.Pow(scalableGrowth, float64(i)))
+	p := sf.p * (1 - scalableRatio) * math.Pow(scalableRatio, float64(i))
+	return capacity, p
+}
+
+func (sf *ScalableBloomFilter) grow() {
+	n, p := sf.sliceParams(len(sf.slices))
+	sf.slices = append(sf.slices, NewBloomFilterWithFPR(n, p, sf.opts...))
+	sf.count = 0
+}
+
+// Add adds element to filter. Elements which are probably in the filter already are skipped,
+// so duplicates do not fill the slices. The filter grows, so it always returns true.
+func (sf *ScalableBloomFilter) Add(item []byte) bool {
+	if sf.Contains(item) {
+		return true
+	}
+
+	if capacity, _ := sf.sliceParams(len(sf.slices) - 1); sf.count >= capacity {
+		sf.grow()
+	}
+	sf.slices[len(sf.slices)-1].Add(item)
+	sf.count++
+	return true
+}
+
+// Contains checks is element "probably exists", or "certainly does not exist" in the filter
+func (sf *ScalableBloomFilter) Contains(item []byte) bool {
+	// the newest slice is the biggest, so it is the most likely to have the item
+	for i := len(sf.slices) - 1; i >= 0; i-- {
+		if sf.slices[i].Contains(item) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count returns number of added items
+func (sf *ScalableBloomFilter) Count() uint64 {
+	n := sf.count
+	for i := range len(sf.slices) - 1 {
+		capacity, _ := sf.sliceParams(i)
+		n += capacity
+	}
+	return n
+}
+
+// SizeBits returns total size of all slices in bits
+func (sf *ScalableBloomFilter) SizeBits() uint64 {
+	var n uint64
+	for _, s := range sf.slices {
+		n += s.SizeBits()
+	}
+	return n
+}
+
+// SliceCount returns number of slices
+func (sf *ScalableBloomFilter) SliceCount() int {
+	return len(sf.slices)
+}
+
+// EstimatedFPR returns current false positive rate, which is the chance that any slice gives a false positive
+func (sf *ScalableBloomFilter) EstimatedFPR() float64 {
+	miss := 1.0
+	for _, s := range sf.slices {
+		miss *= 1 - s.EstimatedFPR()
+	}
+	return 1 - miss
+}
+
+// Binary format, all numbers are little endian:
+//
+//	magic "SBLF" | version uint8 | reserved [3]uint8 | p float64 | initial uint64 | count uint64 |
+//	slice count uint32 | (slice size uint32 | BloomFilter binary)... | crc32 uint32
+const (
+	scalableMagic      = "SBLF"
+	scalableHeaderSize = 36
+)
+
+// MarshalBinary encodes the filter, the hash function of slices must be portable.
+// It fails if an encoded slice does not fit its 4-byte length prefix.
+func (sf *ScalableBloomFilter) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 0, scalableHeaderSize)
+	buf = append(buf, scalableMagic...)
+	buf = append(buf, binaryVersion, 0, 0, 0)
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(sf.p))
+	buf = binary.LittleEndian.AppendUint64(buf, sf.initial)
+	buf = binary.LittleEndian.AppendUint64(buf, sf.count)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(len(sf.slices)))
+
+	for _, s := range sf.slices {
+		data, err := s.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		size, err := sliceSize(len(data))
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.LittleEndian.AppendUint32(buf, size)
+		buf = append(buf, data...)
+	}
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf)), nil
+}
+
+// sliceSize converts length of an encoded slice to its 4-byte length prefix
+func sliceSize(n int) (uint32, error) {
+	if uint64(n) > math.MaxUint32 {
+		return 0, fmt.Errorf("encoded slice of %d bytes does not fit into uint32 length: %w", n, base.ErrInvalidData)
+	}
+	return uint32(n), nil
+}
+
+// UnmarshalBinary replaces the filter with the decoded one. New slices use the hash function of the decoded ones.
+func (sf *ScalableBloomFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < scalableHeaderSize+checksumSize || string(data[:4]) != scalableMagic {
+		return base.ErrInvalidData
+	}
+	if data[4] != binaryVersion {
+		return base.ErrVersion
+	}
+
+	body := data[:len(data)-checksumSize]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(body):]) {
+		return base.ErrChecksum
+	}
+
+	decoded := ScalableBloomFilter{
+		p:       math.Float64frombits(binary.LittleEndian.Uint64(body[8:16])),
+		initial: binary.LittleEndian.Uint64(body[16:24]),
+		count:   binary.LittleEndian.Uint64(body[24:32]),
+	}
+	sliceCount := binary.LittleEndian.Uint32(body[32:36])
+	if sliceCount == 0 || decoded.initial == 0 || !(decoded.p > 0 && decoded.p < 1) {
+		return base.ErrInvalidData
+	}
+
+	rest := body[scalableHeaderSize:]
+	for range sliceCount {
+		if len(rest) < 4 {
+			return base.ErrInvalidData
+		}
+		size := binary.LittleEndian.Uint32(rest)
+		rest = rest[4:]
+		if uint64(len(rest)) < uint64(size) {
+			return base.ErrInvalidData
+		}
+
+		s := new(BloomFilter)
+		if err := s.UnmarshalBinary(rest[:size]); err != nil {
+			return err
+		}
+		decoded.slices = append(decoded.slices, s)
+		rest = rest[size:]
+	}
+	if len(rest) != 0 {
+		return base.ErrInvalidData
+	}
+
+	decoded.opts = []Option{WithHasher(decoded.slices[0].hasher)}
+	*sf = decoded
+	return nil
+}
+
+// WriteTo writes the binary encoding of the filter to w
+func (sf *ScalableBloomFilter) WriteTo(w io.Writer) (int64, error) {
+	data, err := sf.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadFrom reads one filter from r, it does not read past the end of the filter.
+// Slices are read one by one by their length prefix, so the buffer grows only as data arrives.
+func (sf *ScalableBloomFilter) ReadFrom(r io.Reader) (int64, error) {
+	var buf bytes.Buffer
+	read := func(n int64) error {
+		_, err := io.CopyN(&buf, r, n)
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+
+	if err := read(scalableHeaderSize); err != nil {
+		return int64(buf.Len()), err
+	}
+	header := buf.Bytes()
+	if string(header[:4]) != scalableMagic {
+		return int64(buf.Len()), base.ErrInvalidData
+	}
+	if header[4] != binaryVersion {
+		return int64(buf.Len()), base.ErrVersion
+	}
+
+	sliceCount := binary.LittleEndian.Uint32(header[32:36])
+	for range sliceCount {
+		if err := read(4); err != nil {
+			return int64(buf.Len()), err
+		}
+		size := binary.LittleEndian.Uint32(buf.Bytes()[buf.Len()-4:])
+		if err := read(int64(size)); err != nil {
+			return int64(buf.Len()), err
+		}
+	}
+	if err := read(checksumSize); err != nil {
+		return int64(buf.Len()), err
+	}
+
+	return int64(buf.Len()), sf.UnmarshalBinary(buf.Bytes())
+}
@@ -0,0 +1,163 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	base "go-helloworld/filter"
+)
+
+func TestScalableBloomFilter(t *testing.T) {
+	t.Run("FPR stays bounded past the initial size", func(t *testing.T) {
+		const p = 0.01
+		sf := NewScalableBloomFilter(100, p)
+		rnd := rand.New(rand.NewSource(1))
+
+		items := make([][]byte, 20000)
+		for i := range items {
+			items[i] = randomItem(rnd, "in")
+			sf.Add(items[i])
+		}
+		for _, item := range items {
+			if !sf.Contains(item) {
+				t.Fatalf("expected %s to be found", item)
+			}
+		}
+
+		if sf.SliceCount() < 5 {
+			t.Fatalf("expected the filter to grow, got %d slices", sf.SliceCount())
+		}
+		if est := sf.EstimatedFPR(); est > p {
+			t.Fatalf("estimated FPR %.5f is above the bound %.5f", est, p)
+		}
+
+		falsePositives := 0
+		const probes = 100000
+		for i := 0; i < probes; i++ {
+			if sf.Contains(randomItem(rnd, "out")) {
+				falsePositives++
+			}
+		}
+		if measured := float64(falsePositives) / probes; measured > p {
+			t.Fatalf("measured FPR %.5f is above the bound %.5f", measured, p)
+		}
+
+		// a plain filter sized for the initial items degrades badly with the same load
+		plain := NewBloomFilterWithFPR(100, p)
+		for _, item := range items {
+			plain.Add(item)
+		}
+		if plain.EstimatedFPR() < 0.5 {
+			t.Fatalf("expected overloaded plain filter to degrade, got %.5f", plain.EstimatedFPR())
+		}
+	})
+
+	t.Run("Duplicates do not grow the filter", func(t *testing.T) {
+		sf := NewScalableBloomFilter(10, 0.01)
+		for i := 0; i < 1000; i++ {
+			sf.Add([]byte("apple"))
+		}
+		if sf.Count() != 1 || sf.SliceCount() != 1 {
+			t.Fatalf("expected 1 item in 1 slice, got %d items in %d slices", sf.Count(), sf.SliceCount())
+		}
+	})
+}
+
+func TestScalableBloomFilterBinary(t *testing.T) {
+	src := NewScalableBloomFilter(50, 0.01, WithHasher(DefaultXXHasher))
+	rnd := rand.New(rand.NewSource(1))
+	items := make([][]byte, 500)
+	for i := range items {
+		items[i] = randomItem(rnd, "in")
+		src.Add(items[i])
+	}
+
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var dst ScalableBloomFilter
+	if err := dst.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if dst.SliceCount() != src.SliceCount() || dst.Count() != src.Count() {
+		t.Fatalf("expected %d slices with %d items, got %d with %d", src.SliceCount(), src.Count(), dst.SliceCount(), dst.Count())
+	}
+	for _, item := range items {
+		if !dst.Contains(item) {
+			t.Fatalf("expected %s to be found after round trip", item)
+		}
+	}
+
+	// the decoded filter keeps growing with the same hash function
+	for i := 0; i < 1000; i++ {
+		dst.Add(randomItem(rnd, "more"))
+	}
+	if last := dst.slices[len(dst.slices)-1]; last.hasher != DefaultXXHasher {
+		t.Fatal("expected new slices to use the decoded hash function")
+	}
+
+	corrupted := bytes.Clone(data)
+	corrupted[len(corrupted)/2] ^= 1
+	if err := dst.UnmarshalBinary(corrupted); !errors.Is(err, base.ErrChecksum) {
+		t.Fatalf("expected base.ErrChecksum, got %v", err)
+	}
+	if err := dst.UnmarshalBinary(data[:10]); !errors.Is(err, base.ErrInvalidData) {
+		t.Fatalf("expected base.ErrInvalidData, got %v", err)
+	}
+
+	if _, err := NewScalableBloomFilter(10, 0.01, WithHasher(NewMaphashHasher())).MarshalBinary(); !errors.Is(err, ErrNotPortable) {
+		t.Fatalf("expected ErrNotPortable, got %v", err)
+	}
+
+	// slices over 4 GiB can not be built in a test, so the length prefix check is tested alone
+	if _, err := sliceSize(math.MaxUint32 + 1); !errors.Is(err, base.ErrInvalidData) {
+		t.Fatalf("expected ErrInvalidData for a too long slice, got %v", err)
+	}
+}
+
+func TestScalableBloomFilterStream(t *testing.T) {
+	src := NewScalableBloomFilter(50, 0.01)
+	rnd := rand.New(rand.NewSource(2))
+	for range 300 {
+		src.Add(randomItem(rnd, "in"))
+	}
+
+	// two filters one after another, ReadFrom must stop at the end of the first one
+	var buf bytes.Buffer
+	written, err := src.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	src.WriteTo(&buf)
+	data := bytes.Clone(buf.Bytes())
+
+	for i := range 2 {
+		var dst ScalableBloomFilter
+		read, err := dst.ReadFrom(&buf)
+		if err != nil {
+			t.Fatalf("read filter %d: %v", i, err)
+		}
+		if read != written || dst.Count() != src.Count() || dst.SliceCount() != src.SliceCount() {
+			t.Fatalf("filter %d: expected %d bytes and %d items, got %d and %d", i, written, src.Count(), read, dst.Count())
+		}
+	}
+
+	var dst ScalableBloomFilter
+	if _, err := dst.ReadFrom(bytes.NewReader(data[:written-1])); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected unexpected EOF, got %v", err)
+	}
+
+	// a huge slice size must fail on the missing data instead of allocating it upfront
+	huge := bytes.Clone(data[:scalableHeaderSize+4])
+	binary.LittleEndian.PutUint32(huge[scalableHeaderSize:], 1<<31)
+	if _, err := dst.ReadFrom(bytes.NewReader(huge)); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected unexpected EOF, got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package filter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"slices"
+
+	base "go-helloworld/filter"
+)
+
+// Binary format, all numbers are little endian:
+//
+//	magic "CKOF" | version uint8 | fingerprint bits uint8 | bucket size uint8 | victim used uint8 |
+//	bucket count uint32 | max kicks uint32 | count uint64 | rng state uint64 |
+//	victim fingerprint uint32 | victim index uint32 | table []byte | crc32 uint32
+//
+// crc32 (IEEE) covers everything before it. The rng state is stored, so a decoded filter
+// makes the same eviction chains as the original one.
+const (
+	binaryMagic      = "CKOF"
+	binaryVersion    = 1
+	binaryHeaderSize = 40
+	checksumSize     = 4
+)
+
+// MarshalBinary encodes the filter, it fails if the bucket size or max kicks do not fit the header fields
+func (cf *CuckooFilter) MarshalBinary() ([]byte, error) {
+	if cf.bucketSize > math.MaxUint8 {
+		return nil, fmt.Errorf("bucket size %d does not fit into one byte: %w", cf.bucketSize, base.ErrInvalidData)
+	}
+	if cf.maxKicks < 0 || cf.maxKicks > math.MaxUint32 {
+		return nil, fmt.Errorf("max kicks %d does not fit into uint32: %w", cf.maxKicks, base.ErrInvalidData)
+	}
+
+	var victimUsed byte
+	if cf.victim.used {
+		victimUsed = 1
+	}
+
+	buf := make([]byte, 0, binaryHeaderSize+len(cf.table)+checksumSize)
+	buf = append(buf, binaryMagic...)
+	buf = append(buf, binaryVersion, byte(cf.layout.bits), byte(cf.bucketSize), victimUsed)
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(cf.bucketCount()))
+	buf = binary.LittleEndian.AppendUint32(buf, uint32(cf.maxKicks))
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(cf.count))
+	buf = binary.LittleEndian.AppendUint64(buf, cf.rnd.state)
+	buf = binary.LittleEndian.AppendUint32(buf, cf.victim.fp)
+	buf = binary.LittleEndian.AppendUint32(buf, cf.victim.index)
+	buf = append(buf, cf.table...)
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf)), nil
+}
+
+// UnmarshalBinary replaces the filter with the decoded one
+func (cf *CuckooFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < binaryHeaderSize+checksumSize || string(data[:4]) != binaryMagic {
+		return base.ErrInvalidData
+	}
+	if data[4] != binaryVersion {
+		return base.ErrVersion
+	}
+
+	fingerprintBits, bucketSize := int(data[5]), int(data[6])
+	bucketCount := binary.LittleEndian.Uint32(data[8:12])
+	if !slices.Contains(fingerprintWidths, fingerprintBits) || bucketSize == 0 ||
+		bucketCount == 0 || bucketCount&(bucketCount-1) != 0 {
+		return base.ErrInvalidData
+	}
+
+	if binary.LittleEndian.Uint32(data[36:40]) >= bucketCount {
+		return base.ErrInvalidData
+	}
+
+	l := newLayout(fingerprintBits, bucketSize)
+	if uint64(len(data)) != binaryHeaderSize+uint64(bucketCount)*uint64(l.bucketBytes())+checksumSize {
+		return base.ErrInvalidData
+	}
+
+	body := data[:len(data)-checksumSize]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(body):]) {
+		return base.ErrChecksum
+	}
+
+	*cf = CuckooFilter{
+		table:      slices.Clone(body[binaryHeaderSize:]),
+		bucketSize: bucketSize,
+		maxKicks:   int(binary.LittleEndian.Uint32(body[12:16])),
+		layout:     l,
+		mask:       bucketCount - 1,
+		count:      int(binary.LittleEndian.Uint64(body[16:24])),
+		rnd:        rng{state: max(binary.LittleEndian.Uint64(body[24:32]), 1)},
+		victim: victim{
+			used:  data[7] == 1,
+			fp:    binary.LittleEndian.Uint32(body[32:36]),
+			index: binary.LittleEndian.Uint32(body[36:40]),
+		},
+	}
+	return nil
+}
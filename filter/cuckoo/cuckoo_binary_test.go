@@ -0,0 +1,121 @@
+package filter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	base "go-helloworld/filter"
+)
+
+// TestSeedIsReproducible checks that filters with the same seed end up with the same content.
+func TestSeedIsReproducible(t *testing.T) {
+	build := func() *CuckooFilter {
+		cf := NewCuckooFilter(64, 4, 500, WithSeed(42))
+		for i := 0; i < 250; i++ {
+			cf.Insert([]byte(fmt.Sprintf("key-%d", i)))
+		}
+		return cf
+	}
+
+	a, b := build(), build()
+	if !bytes.Equal(a.table, b.table) || a.victim != b.victim {
+		t.Fatal("expected filters with the same seed to be equal")
+	}
+}
+
+// TestMarshalBinary checks round trip, that the decoded filter keeps kicking the same way and corrupted data.
+func TestMarshalBinary(t *testing.T) {
+	for _, fpr := range []float64{0.05, 0.003, 0.0002, 0.00001} {
+		t.Run(fmt.Sprintf("fpr=%g", fpr), func(t *testing.T) {
+			src := NewCuckooFilterWithFPR(1000, fpr, WithSeed(7))
+			for i := 0; i < 900; i++ {
+				src.Insert([]byte(fmt.Sprintf("key-%d", i)))
+			}
+
+			data, err := src.MarshalBinary()
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+
+			var dst CuckooFilter
+			if err := dst.UnmarshalBinary(data); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if dst.Count() != src.Count() || dst.FingerprintBits() != src.FingerprintBits() {
+				t.Fatalf("expected count %d and %d-bit fingerprints, got %d and %d",
+					src.Count(), src.FingerprintBits(), dst.Count(), dst.FingerprintBits())
+			}
+			for i := 0; i < 900; i++ {
+				if !dst.Contains([]byte(fmt.Sprintf("key-%d", i))) {
+					t.Fatalf("false negative after round trip for key-%d", i)
+				}
+			}
+
+			// both filters continue with the same rng state
+			for i := 900; i < 1100; i++ {
+				key := []byte(fmt.Sprintf("key-%d", i))
+				if src.Insert(key) != dst.Insert(key) {
+					t.Fatalf("expected the same insert result for %s", key)
+				}
+			}
+			if !bytes.Equal(src.table, dst.table) {
+				t.Fatal("expected the same content after more inserts")
+			}
+		})
+	}
+
+	t.Run("Victim is kept", func(t *testing.T) {
+		src := NewCuckooFilter(16, 2, 20, WithSeed(1))
+		for i := 0; !src.victim.used; i++ {
+			src.Insert([]byte(fmt.Sprintf("key-%d", i)))
+		}
+
+		data, _ := src.MarshalBinary()
+		var dst CuckooFilter
+		if err := dst.UnmarshalBinary(data); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if dst.victim != src.victim {
+			t.Fatalf("expected victim %+v, got %+v", src.victim, dst.victim)
+		}
+	})
+
+	t.Run("Corrupted data", func(t *testing.T) {
+		cf := NewCuckooFilter(16, 4, 20)
+		cf.Insert([]byte("apple"))
+		data, _ := cf.MarshalBinary()
+
+		corrupt := func(i int, b byte) []byte {
+			c := bytes.Clone(data)
+			c[i] = b
+			return c
+		}
+
+		cases := map[string]struct {
+			data []byte
+			err  error
+		}{
+			"magic":       {data: corrupt(0, 'X'), err: base.ErrInvalidData},
+			"version":     {data: corrupt(4, 99), err: base.ErrVersion},
+			"width":       {data: corrupt(5, 9), err: base.ErrInvalidData},
+			"table":       {data: corrupt(binaryHeaderSize, data[binaryHeaderSize]^1), err: base.ErrChecksum},
+			"truncated":   {data: data[:len(data)-1], err: base.ErrInvalidData},
+			"not pow2":    {data: corrupt(8, 3), err: base.ErrInvalidData},
+			"short input": {data: data[:8], err: base.ErrInvalidData},
+		}
+		for name, tc := range cases {
+			var dst CuckooFilter
+			if err := dst.UnmarshalBinary(tc.data); !errors.Is(err, tc.err) {
+				t.Fatalf("%s: expected %v, got %v", name, tc.err, err)
+			}
+		}
+	})
+	t.Run("Header overflow", func(t *testing.T) {
+		cf := NewCuckooFilter(4, 300, 10)
+		if _, err := cf.MarshalBinary(); !errors.Is(err, base.ErrInvalidData) {
+			t.Fatalf("expected bucket size 300 to be rejected, got %v", err)
+		}
+	})
+}
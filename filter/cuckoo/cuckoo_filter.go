@@ -1,62 +1,180 @@
 package filter
 
 import (
-	"hash/fnv"
+	"math"
 	"math/rand"
+
+	"go-helloworld/internal/hashing"
 )
 
+// supported fingerprint widths in bits
+var fingerprintWidths = []int{8, 12, 16, 32}
+
+// Bucket is a view of one bucket in the filter table, it is not stored and is made on every access.
 type Bucket struct {
-	slots []uint8 // 8-bit fingerprints
+	slots []byte // fingerprints packed with layout.bits each, zero fingerprint marks an empty slot
+}
+
+// layout describes how fingerprints are packed in a bucket
+type layout struct {
+	bits int    // fingerprint width
+	size int    // number of slots per bucket
+	mask uint32 // fingerprint mask
+}
+
+func newLayout(fingerprintBits int, bucketSize int) layout {
+	return layout{
+		bits: fingerprintBits,
+		size: bucketSize,
+		mask: uint32(1<<fingerprintBits - 1),
+	}
+}
+
+// bucketBytes returns size of a packed bucket in bytes
+func (l layout) bucketBytes() int {
+	return (l.size*l.bits + 7) / 8
 }
 
 type CuckooFilter struct {
-	buckets    []Bucket // array of buckets
-	bucketSize int      // number of slots per bucket
-	maxKicks   int      // max number of evictions during insert
+	table      []byte // all buckets packed one after another, the number of buckets is a power of two
+	bucketSize int    // number of slots per bucket
+	maxKicks   int    // max number of evictions during insert
+	layout     layout // fingerprint packing
+	mask       uint32 // bucket index mask
+	count      int    // number of stored fingerprints including the victim
+	victim     victim // fingerprint which did not fit after the eviction chain
+	rnd        rng    // picks slots to kick, its state is serialized with the filter
+}
+
+// Option configures the filter on creation
+type Option func(cf *CuckooFilter)
+
+// WithSeed makes the eviction chains reproducible: filters with the same seed and the same inserts
+// have the same content. By default the seed is random.
+func WithSeed(seed uint64) Option {
+	return func(cf *CuckooFilter) {
+		cf.rnd = newRNG(seed)
+	}
+}
+
+// rng is a xorshift64* generator, unlike math/rand its state is a single number which can be serialized
+type rng struct {
+	state uint64
+}
+
+func newRNG(seed uint64) rng {
+	// the state must not be zero, splitmix64 step spreads small seeds
+	seed += 0x9e3779b97f4a7c15
+	seed = (seed ^ seed>>30) * 0xbf58476d1ce4e5b9
+	seed = (seed ^ seed>>27) * 0x94d049bb133111eb
+	seed ^= seed >> 31
+	return rng{state: max(seed, 1)}
+}
+
+// intn returns a number in [0, n)
+func (r *rng) intn(n int) int {
+	r.state ^= r.state >> 12
+	r.state ^= r.state << 25
+	r.state ^= r.state >> 27
+	return int((r.state * 0x2545f4914f6cdd1d >> 32) % uint64(n))
+}
+
+// victim keeps the last kicked fingerprint when the eviction chain fails, so no inserted key is lost.
+// While the victim is used the filter is full and Insert fails.
+type victim struct {
+	used  bool
+	fp    uint32
+	index uint32 // bucket where the fingerprint should be
 }
 
-func NewCuckooFilter(bucketCount int, bucketSize int, maxKicks int) *CuckooFilter {
-	buckets := make([]Bucket, bucketCount)
+// NewCuckooFilter creates filter with 8-bit fingerprints, bucketCount is rounded up to a power of two.
+func NewCuckooFilter(bucketCount int, bucketSize int, maxKicks int, opts ...Option) *CuckooFilter {
+	return newCuckooFilter(bucketCount, bucketSize, 8, maxKicks, opts...)
+}
+
+// NewCuckooFilterWithFPR creates filter for capacity keys with false positive rate fpr.
+// It uses 4-slot buckets, which can be filled up to 95%, and the narrowest fingerprint
+// of 8, 12, 16 or 32 bits which gives fpr, as the rate is about 2*bucketSize/2^bits.
+func NewCuckooFilterWithFPR(capacity int, fpr float64, opts ...Option) *CuckooFilter {
+	const (
+		bucketSize    = 4
+		maxLoadFactor = 0.95
+		maxKicks      = 500
+	)
+	if fpr <= 0 || fpr >= 1 {
+		panic("filter: false positive rate must be in (0, 1)")
+	}
 
-	for i := range buckets {
-		buckets[i] = Bucket{
-			slots: make([]uint8, 0, bucketSize),
+	need := int(math.Ceil(math.Log2(2 * bucketSize / fpr)))
+	width := fingerprintWidths[len(fingerprintWidths)-1]
+	for _, w := range fingerprintWidths {
+		if w >= need {
+			width = w
+			break
 		}
 	}
 
-	return &CuckooFilter{
-		buckets:    buckets,
+	bucketCount := int(math.Ceil(float64(max(capacity, 1)) / bucketSize / maxLoadFactor))
+	return newCuckooFilter(bucketCount, bucketSize, width, maxKicks, opts...)
+}
+
+func newCuckooFilter(bucketCount int, bucketSize int, fingerprintBits int, maxKicks int, opts ...Option) *CuckooFilter {
+	// power of two bucket count keeps index2 an involution: index2(index2(i, fp), fp) == i
+	n := 1
+	for n < bucketCount {
+		n <<= 1
+	}
+
+	l := newLayout(fingerprintBits, bucketSize)
+
+	cf := &CuckooFilter{
+		table:      make([]byte, n*l.bucketBytes()),
 		bucketSize: bucketSize,
 		maxKicks:   maxKicks,
+		layout:     l,
+		mask:       uint32(n - 1),
+		rnd:        newRNG(rand.Uint64()),
+	}
+	for _, opt := range opts {
+		opt(cf)
 	}
+	return cf
 }
 
-func fingerprint(key []byte) uint8 {
-	h := fnv.New32a()
-	h.Write(key)
+// bucketCount returns number of buckets
+func (cf *CuckooFilter) bucketCount() int {
+	return int(cf.mask) + 1
+}
 
-	// returns 1 to 255 (zero is skipped and reserved for empty slots)
-	return uint8(h.Sum32()%255 + 1)
+// bucket returns view of the i-th bucket in the table
+func (cf *CuckooFilter) bucket(i uint32) Bucket {
+	size := cf.layout.bucketBytes()
+	off := int(i) * size
+	return Bucket{slots: cf.table[off : off+size : off+size]}
 }
 
-// index1 computes the primary bucket index for a key.
-func (cf *CuckooFilter) index1(key []byte) uint32 {
-	h := fnv.New32a()
-	h.Write(key)
+// fingerprint takes the high half of the key hash, the low half is used for the bucket index.
+func (cf *CuckooFilter) fingerprint(h uint64) uint32 {
+	// returns 1 to 2^bits-1 (zero is skipped and reserved for empty slots)
+	return uint32((h>>32)%uint64(cf.layout.mask)) + 1
+}
 
-	return h.Sum32() % uint32(len(cf.buckets))
+// index1 computes the primary bucket index for a key hash.
+func (cf *CuckooFilter) index1(h uint64) uint32 {
+	return uint32(h) & cf.mask
 }
 
 // index2 computes the alternate bucket index using XOR with fingerprint hash.
-func (cf *CuckooFilter) index2(i1 uint32, fp uint8) uint32 {
-	h := uint32(fp) * 0x5bd1e995
-	return (i1 ^ h) % uint32(len(cf.buckets))
+func (cf *CuckooFilter) index2(i1 uint32, fp uint32) uint32 {
+	h := fp * 0x5bd1e995
+	return (i1 ^ h) & cf.mask
 }
 
 // findIndexes calculates fingerprint and both candidate bucket indexes for a key.
-func (cf *CuckooFilter) findIndexes(key []byte) (fp uint8, i1, i2 uint32) {
-	fp = fingerprint(key)
-	i1 = cf.index1(key)
+func (cf *CuckooFilter) findIndexes(key []byte) (fp uint32, i1, i2 uint32) {
+	h := hashing.Sum64(key)
+	fp = cf.fingerprint(h)
+	i1 = cf.index1(h)
 	i2 = cf.index2(i1, fp)
 
 	return fp, i1, i2
@@ -67,37 +185,163 @@ func (cf *CuckooFilter) Contains(key []byte) bool {
 	fp, i1, i2 := cf.findIndexes(key)
 
 	// false means the key is "definitely not" present.
-	return cf.buckets[i1].has(fp) || cf.buckets[i2].has(fp)
+	return cf.bucket(i1).has(cf.layout, fp) || cf.bucket(i2).has(cf.layout, fp) || cf.victimHas(fp, i1, i2)
+}
+
+// victimHas checks if fingerprint of a key with buckets i1 and i2 is in the victim slot.
+func (cf *CuckooFilter) victimHas(fp uint32, i1, i2 uint32) bool {
+	return cf.victim.used && cf.victim.fp == fp && (cf.victim.index == i1 || cf.victim.index == i2)
 }
 
 // Insert attempts to insert a key into the filter.
+// It returns false if the filter is full, in that case the filter is not changed.
 func (cf *CuckooFilter) Insert(key []byte) bool {
+	if cf.victim.used {
+		return false
+	}
+
 	fp, i1, i2 := cf.findIndexes(key)
+	cf.count++
 
 	// try direct insertion into either bucket
-	if cf.buckets[i1].insert(fp) || cf.buckets[i2].insert(fp) {
+	if cf.bucket(i1).insert(cf.layout, fp) || cf.bucket(i2).insert(cf.layout, fp) {
 		return true
 	}
 
-	// start cuckoo eviction chain
-	i := i1
+	cf.kick(fp, i1)
+	return true
+}
+
+// kick runs the cuckoo eviction chain starting from bucket i. If the chain fails (> maxKicks),
+// the last kicked fingerprint belongs to another key, so it is kept in the victim slot.
+func (cf *CuckooFilter) kick(fp uint32, i uint32) {
 	for n := 0; n < cf.maxKicks; n++ {
-		fp = cf.buckets[i].swapRandom(fp)
+		fp = cf.bucket(i).swap(cf.layout, cf.rnd.intn(cf.layout.size), fp)
 		i = cf.index2(i, fp)
 
-		if cf.buckets[i].insert(fp) {
-			return true
+		if cf.bucket(i).insert(cf.layout, fp) {
+			return
 		}
 	}
 
-	// Insert failed (> maxKicks)
+	cf.victim = victim{used: true, fp: fp, index: i}
+}
+
+// Add is Insert under the name shared with other filters.
+func (cf *CuckooFilter) Add(key []byte) bool {
+	return cf.Insert(key)
+}
+
+// Remove is Delete under the name shared with other filters.
+func (cf *CuckooFilter) Remove(key []byte) bool {
+	return cf.Delete(key)
+}
+
+// Delete removes one copy of the key and reports whether it was found.
+// Only keys which were inserted before may be deleted, deleting a false positive removes another key.
+func (cf *CuckooFilter) Delete(key []byte) bool {
+	fp, i1, i2 := cf.findIndexes(key)
+
+	switch {
+	case cf.bucket(i1).delete(cf.layout, fp), cf.bucket(i2).delete(cf.layout, fp):
+		cf.count--
+		// there is a free slot now, so the victim may fit
+		cf.reinsertVictim()
+		return true
+	case cf.victimHas(fp, i1, i2):
+		cf.victim = victim{}
+		cf.count--
+		return true
+	}
 	return false
 }
 
+// reinsertVictim moves the victim back to buckets, the full eviction chain is run again.
+func (cf *CuckooFilter) reinsertVictim() {
+	if !cf.victim.used {
+		return
+	}
+
+	v := cf.victim
+	cf.victim = victim{}
+
+	if cf.bucket(v.index).insert(cf.layout, v.fp) {
+		return
+	}
+	if alt := cf.index2(v.index, v.fp); cf.bucket(alt).insert(cf.layout, v.fp) {
+		return
+	}
+	cf.kick(v.fp, v.index)
+}
+
+// Count returns number of stored keys.
+func (cf *CuckooFilter) Count() int {
+	return cf.count
+}
+
+// LoadFactor returns share of occupied slots, cuckoo filters with 4-slot buckets fill up to about 95%.
+func (cf *CuckooFilter) LoadFactor() float64 {
+	return float64(cf.count) / float64(cf.bucketCount()*cf.bucketSize)
+}
+
+// SizeBits returns size of the slot table in bits.
+func (cf *CuckooFilter) SizeBits() uint64 {
+	return uint64(len(cf.table)) * 8
+}
+
+// FingerprintBits returns fingerprint width in bits.
+func (cf *CuckooFilter) FingerprintBits() int {
+	return cf.layout.bits
+}
+
+// Reset removes all keys.
+func (cf *CuckooFilter) Reset() {
+	clear(cf.table)
+	cf.count = 0
+	cf.victim = victim{}
+}
+
+// get returns fingerprint in slot i.
+func (b Bucket) get(l layout, i int) uint32 {
+	bit := i * l.bits
+	var v uint64
+	for j, off := 0, bit/8; j < 5 && off+j < len(b.slots); j++ {
+		v |= uint64(b.slots[off+j]) << (8 * j)
+	}
+	return uint32(v>>(bit%8)) & l.mask
+}
+
+// set writes fingerprint to slot i.
+func (b Bucket) set(l layout, i int, fp uint32) {
+	bit := i * l.bits
+	for j, off := 0, bit/8; j < 5 && off+j < len(b.slots); j++ {
+		shift := 8*j - bit%8
+		// bits of the slot which are stored in byte off+j
+		var m, v uint64
+		if shift >= 0 {
+			m, v = uint64(l.mask)>>shift, uint64(fp)>>shift
+		} else {
+			m, v = uint64(l.mask)<<-shift, uint64(fp)<<-shift
+		}
+		b.slots[off+j] = b.slots[off+j]&^byte(m) | byte(v&m)
+	}
+}
+
 // has checks if fingerprint exists in the bucket.
-func (b *Bucket) has(fp uint8) bool {
-	for _, v := range b.slots {
-		if v == fp {
+func (b Bucket) has(l layout, fp uint32) bool {
+	for i := 0; i < l.size; i++ {
+		if b.get(l, i) == fp {
+			return true
+		}
+	}
+	return false
+}
+
+// delete removes one copy of fingerprint from bucket.
+func (b Bucket) delete(l layout, fp uint32) bool {
+	for i := 0; i < l.size; i++ {
+		if b.get(l, i) == fp {
+			b.set(l, i, 0)
 			return true
 		}
 	}
@@ -105,19 +349,20 @@ func (b *Bucket) has(fp uint8) bool {
 }
 
 // insert attempts to add fingerprint into bucket if space is available.
-func (b *Bucket) insert(fp uint8) bool {
-	if len(b.slots) < cap(b.slots) {
-		b.slots = append(b.slots, fp)
-		return true
+func (b Bucket) insert(l layout, fp uint32) bool {
+	for i := 0; i < l.size; i++ {
+		if b.get(l, i) == 0 {
+			b.set(l, i, fp)
+			return true
+		}
 	}
 	return false
 }
 
-// swapRandom randomly evicts one fingerprint and replaces it with new one.
-func (b *Bucket) swapRandom(fp uint8) uint8 {
-	i := rand.Intn(len(b.slots))
-	old := b.slots[i]
-	b.slots[i] = fp
+// swap evicts fingerprint in slot i and replaces it with new one.
+func (b Bucket) swap(l layout, i int, fp uint32) uint32 {
+	old := b.get(l, i)
+	b.set(l, i, fp)
 
 	// returns the evicted fingerprint.
 	return old
@@ -8,9 +8,7 @@ import (
 
 // TestInsertAndContains verifies that inserted keys are always found.
 func TestInsertAndContains(t *testing.T) {
-	rand.Seed(42)
-
-	cf := NewCuckooFilter(256, 4, 500)
+	cf := NewCuckooFilter(256, 4, 500, WithSeed(42))
 
 	keys := [][]byte{
 		[]byte("apple"),
@@ -35,9 +33,7 @@ func TestInsertAndContains(t *testing.T) {
 
 // TestNoFalseNegatives ensures that filter never returns false for inserted keys.
 func TestNoFalseNegatives(t *testing.T) {
-	rand.Seed(1)
-
-	cf := NewCuckooFilter(512, 4, 500)
+	cf := NewCuckooFilter(512, 4, 500, WithSeed(1))
 
 	for i := 0; i < 1000; i++ {
 		key := []byte(fmt.Sprintf("key-%d", i))
@@ -54,9 +50,7 @@ func TestNoFalseNegatives(t *testing.T) {
 
 // TestFalsePositiveRate checks that false positives exist but are reasonably low.
 func TestFalsePositiveRate(t *testing.T) {
-	rand.Seed(99)
-
-	cf := NewCuckooFilter(1024, 4, 500)
+	cf := NewCuckooFilter(1024, 4, 500, WithSeed(99))
 
 	inserted := 2000
 	queries := 5000
@@ -86,9 +80,7 @@ func TestFalsePositiveRate(t *testing.T) {
 
 // TestInsertOverflow verifies behavior when filter is overfilled.
 func TestInsertOverflow(t *testing.T) {
-	rand.Seed(7)
-
-	cf := NewCuckooFilter(64, 2, 50)
+	cf := NewCuckooFilter(64, 2, 50, WithSeed(7))
 
 	failures := 0
 	for i := 0; i < 1000; i++ {
@@ -102,3 +94,202 @@ func TestInsertOverflow(t *testing.T) {
 		t.Fatal("expected some insert failures, got none")
 	}
 }
+
+// TestDelete verifies that deleted keys are gone and the rest stay.
+func TestDelete(t *testing.T) {
+	cf := NewCuckooFilter(256, 4, 500, WithSeed(3))
+
+	for i := 0; i < 500; i++ {
+		if !cf.Insert([]byte(fmt.Sprintf("key-%d", i))) {
+			t.Fatalf("insert failed at %d", i)
+		}
+	}
+	if cf.Count() != 500 {
+		t.Fatalf("expected count 500, got %d", cf.Count())
+	}
+
+	for i := 0; i < 500; i += 2 {
+		if !cf.Delete([]byte(fmt.Sprintf("key-%d", i))) {
+			t.Fatalf("delete failed for key-%d", i)
+		}
+	}
+	if cf.Count() != 250 {
+		t.Fatalf("expected count 250, got %d", cf.Count())
+	}
+
+	for i := 1; i < 500; i += 2 {
+		if !cf.Contains([]byte(fmt.Sprintf("key-%d", i))) {
+			t.Fatalf("false negative after delete for key-%d", i)
+		}
+	}
+
+	stillFound := 0
+	for i := 0; i < 500; i += 2 {
+		if cf.Contains([]byte(fmt.Sprintf("key-%d", i))) {
+			stillFound++
+		}
+	}
+	if stillFound > 25 {
+		t.Fatalf("expected deleted keys to be gone, %d are still found", stillFound)
+	}
+
+	if cf.Delete([]byte("never-inserted")) {
+		t.Fatal("expected delete of unknown key to fail")
+	}
+}
+
+// TestDuplicates verifies that every inserted copy needs its own delete.
+func TestDuplicates(t *testing.T) {
+	cf := NewCuckooFilter(16, 4, 50)
+	key := []byte("apple")
+
+	cf.Insert(key)
+	cf.Insert(key)
+
+	cf.Delete(key)
+	if !cf.Contains(key) {
+		t.Fatal("expected the second copy to stay")
+	}
+	cf.Delete(key)
+	if cf.Contains(key) {
+		t.Fatal("expected key to be deleted")
+	}
+}
+
+// TestVictim verifies that a failed eviction chain does not lose keys.
+func TestVictim(t *testing.T) {
+	cf := NewCuckooFilter(64, 2, 50, WithSeed(7))
+
+	var inserted [][]byte
+	for i := 0; i < 1000; i++ {
+		key := []byte(fmt.Sprintf("overflow-%d", i))
+		if cf.Insert(key) {
+			inserted = append(inserted, key)
+		}
+	}
+
+	if !cf.victim.used {
+		t.Fatal("expected the victim slot to be used in a full filter")
+	}
+	if cf.Count() != len(inserted) {
+		t.Fatalf("expected count %d, got %d", len(inserted), cf.Count())
+	}
+	for _, key := range inserted {
+		if !cf.Contains(key) {
+			t.Fatalf("false negative for %s", key)
+		}
+	}
+
+	// a free slot lets the victim back to buckets, and the filter accepts keys again
+	if !cf.Delete(inserted[0]) {
+		t.Fatalf("delete failed for %s", inserted[0])
+	}
+	for _, key := range inserted[1:] {
+		if !cf.Contains(key) {
+			t.Fatalf("false negative after delete for %s", key)
+		}
+	}
+}
+
+// TestLoadFactorAndReset checks load factor and that Reset empties the filter.
+func TestLoadFactorAndReset(t *testing.T) {
+	cf := NewCuckooFilter(100, 4, 500)
+
+	for i := 0; i < 200; i++ {
+		cf.Insert([]byte(fmt.Sprintf("key-%d", i)))
+	}
+	if lf := cf.LoadFactor(); lf != 200.0/512 {
+		t.Fatalf("expected load factor %.3f, got %.3f", 200.0/512, lf)
+	}
+
+	cf.Reset()
+	if cf.Count() != 0 || cf.LoadFactor() != 0 || cf.Contains([]byte("key-1")) {
+		t.Fatal("expected Reset to empty the filter")
+	}
+}
+
+// TestPackedSlots checks that packed slots of every width keep their values independently.
+func TestPackedSlots(t *testing.T) {
+	rnd := rand.New(rand.NewSource(5))
+
+	for _, width := range fingerprintWidths {
+		l := newLayout(width, 4)
+		b := Bucket{slots: make([]byte, l.bucketBytes())}
+
+		want := make([]uint32, l.size)
+		for n := 0; n < 1000; n++ {
+			i := rnd.Intn(l.size)
+			want[i] = rnd.Uint32() & l.mask
+			b.set(l, i, want[i])
+
+			for j := range want {
+				if got := b.get(l, j); got != want[j] {
+					t.Fatalf("width %d: slot %d = %x, want %x", width, j, got, want[j])
+				}
+			}
+		}
+	}
+}
+
+// TestNewCuckooFilterWithFPR checks sizing and that the measured FPR is close to the target.
+func TestNewCuckooFilterWithFPR(t *testing.T) {
+	cases := []struct {
+		fpr   float64
+		width int
+	}{
+		{fpr: 0.05, width: 8},
+		{fpr: 0.003, width: 12},
+		{fpr: 0.0002, width: 16},
+		{fpr: 0.00001, width: 32},
+	}
+
+	for _, tc := range cases {
+		t.Run(fmt.Sprintf("fpr=%g", tc.fpr), func(t *testing.T) {
+			const capacity = 10000
+			cf := NewCuckooFilterWithFPR(capacity, tc.fpr)
+
+			if cf.FingerprintBits() != tc.width {
+				t.Fatalf("expected %d-bit fingerprints, got %d", tc.width, cf.FingerprintBits())
+			}
+			if n := cf.bucketCount(); n&(n-1) != 0 || n*cf.bucketSize < capacity {
+				t.Fatalf("expected power of two buckets for %d keys, got %d", capacity, n)
+			}
+
+			for i := 0; i < capacity; i++ {
+				if !cf.Insert([]byte(fmt.Sprintf("inserted-%d", i))) {
+					t.Fatalf("insert failed at %d", i)
+				}
+			}
+			for i := 0; i < capacity; i++ {
+				if !cf.Contains([]byte(fmt.Sprintf("inserted-%d", i))) {
+					t.Fatalf("false negative for inserted-%d", i)
+				}
+			}
+
+			queries := int(20 / tc.fpr)
+			falsePositives := 0
+			for i := 0; i < min(queries, 1000000); i++ {
+				if cf.Contains([]byte(fmt.Sprintf("not-inserted-%d", i))) {
+					falsePositives++
+				}
+			}
+			if fpRate := float64(falsePositives) / float64(min(queries, 1000000)); fpRate > tc.fpr {
+				t.Fatalf("false positive rate %.6f is above target %.6f", fpRate, tc.fpr)
+			}
+		})
+	}
+}
+
+// TestAlternateIndexSymmetry checks that the alternate bucket of the alternate bucket is the original one,
+// even when the requested bucket count is not a power of two.
+func TestAlternateIndexSymmetry(t *testing.T) {
+	cf := NewCuckooFilter(1000, 4, 500)
+
+	for i := uint32(0); i < uint32(cf.bucketCount()); i++ {
+		for _, fp := range []uint32{1, 2, 77, 255} {
+			if back := cf.index2(cf.index2(i, fp), fp); back != i {
+				t.Fatalf("index2 is not symmetric for bucket %d and fingerprint %d: got %d", i, fp, back)
+			}
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// Errors of the binary formats, they are shared by all filter types, so callers can check them with errors.Is
+// without knowing which filter they decode
+var (
+	ErrInvalidData = errors.New("filter: invalid binary data")
+	ErrVersion     = errors.New("filter: unsupported binary version")
+	ErrChecksum    = errors.New("filter: checksum mismatch")
+)
+
+// Filter is a probabilistic set: Contains may return false positives, but never false negatives
+// for items which were added
+type Filter interface {
+	// Add adds the item and returns false if the filter is full and the item was not added
+	Add(item []byte) bool
+	// Contains checks is item "probably exists", or "certainly does not exist" in the filter
+	Contains(item []byte) bool
+}
+
+// Deleter is implemented by filters which can remove items, e.g. counting Bloom and cuckoo filters
+type Deleter interface {
+	// Remove removes the item which was added before and returns false if the item was not found
+	Remove(item []byte) bool
+}
+
+// Sizer is implemented by filters which report their memory, it is used to compute bits per item
+type Sizer interface {
+	SizeBits() uint64
+}
+
+// CanDelete returns the filter as Deleter if it supports removal
+func CanDelete(f Filter) (Deleter, bool) {
+	d, ok := f.(Deleter)
+	return d, ok
+}
+
+// Dataset is the same set of items for every filter in a comparison.
+// Absent items are never added and are used to measure false positives.
+type Dataset struct {
+	Added  [][]byte
+	Absent [][]byte
+}
+
+// NewDataset generates n items to add and probes absent items, the same seed gives the same items
+func NewDataset(n, probes int, seed int64) Dataset {
+	rnd := rand.New(rand.NewSource(seed))
+	item := func(prefix string) []byte {
+		return fmt.Appendf(nil, "%s-%016x", prefix, rnd.Uint64())
+	}
+
+	d := Dataset{
+		Added:  make([][]byte, n),
+		Absent: make([][]byte, probes),
+	}
+	for i := range d.Added {
+		d.Added[i] = item("added")
+	}
+	for i := range d.Absent {
+		d.Absent[i] = item("absent")
+	}
+	return d
+}
+
+// Result is accuracy, size and speed of a filter measured on a dataset
+type Result struct {
+	Name            string
+	Added           int     // items which were added, failed adds are not counted
+	FalseNegatives  int     // added items which were not found, must be zero
+	FPR             float64 // share of absent items which were found
+	BitsPerItem     float64 // zero if the filter is not a Sizer
+	AddNsPerOp      float64
+	ContainsNsPerOp float64
+	CanDelete       bool
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%-20s added=%-7d fpr=%.5f bits/item=%6.2f add=%6.1fns contains=%6.1fns delete=%v",
+		r.Name, r.Added, r.FPR, r.BitsPerItem, r.AddNsPerOp, r.ContainsNsPerOp, r.CanDelete)
+}
+
+// Evaluate adds items of the dataset to an empty filter and measures it
+func Evaluate(name string, f Filter, data Dataset) Result {
+	r := Result{Name: name}
+	_, r.CanDelete = CanDelete(f)
+
+	added := make([][]byte, 0, len(data.Added))
+	start := time.Now()
+	for _, item := range data.Added {
+		if f.Add(item) {
+			added = append(added, item)
+		}
+	}
+	r.AddNsPerOp = nsPerOp(time.Since(start), len(data.Added))
+	r.Added = len(added)
+
+	for _, item := range added {
+		if !f.Contains(item) {
+			r.FalseNegatives++
+		}
+	}
+
+	falsePositives := 0
+	start = time.Now()
+	for _, item := range data.Absent {
+		if f.Contains(item) {
+			falsePositives++
+		}
+	}
+	r.ContainsNsPerOp = nsPerOp(time.Since(start), len(data.Absent))
+
+	if len(data.Absent) > 0 {
+		r.FPR = float64(falsePositives) / float64(len(data.Absent))
+	}
+	if s, ok := f.(Sizer); ok && r.Added > 0 {
+		r.BitsPerItem = float64(s.SizeBits()) / float64(r.Added)
+	}
+	return r
+}
+
+func nsPerOp(d time.Duration, n int) float64 {
+	if n == 0 {
+		return 0
+	}
+	return float64(d.Nanoseconds()) / float64(n)
+}
@@ -0,0 +1,136 @@
+package filter_test
+
+import (
+	"encoding"
+	"errors"
+	"testing"
+
+	"go-helloworld/filter"
+	bloom "go-helloworld/filter/bloom"
+	cuckoo "go-helloworld/filter/cuckoo"
+	xor "go-helloworld/filter/xor"
+)
+
+var (
+	_ filter.Filter = (*bloom.BloomFilter)(nil)
+	_ filter.Filter = (*bloom.CountingBloomFilter)(nil)
+	_ filter.Filter = (*bloom.ScalableBloomFilter)(nil)
+	_ filter.Filter = (*bloom.ConcurrentBloomFilter)(nil)
+	_ filter.Filter = (*cuckoo.CuckooFilter)(nil)
+
+	_ filter.Deleter = (*bloom.CountingBloomFilter)(nil)
+	_ filter.Deleter = (*cuckoo.CuckooFilter)(nil)
+)
+
+const (
+	harnessItems = 20000
+	harnessFPR   = 0.01
+)
+
+// implementations returns every filter sized for harnessItems with harnessFPR
+func implementations() []struct {
+	name string
+	new  func() filter.Filter
+} {
+	return []struct {
+		name string
+		new  func() filter.Filter
+	}{
+		{"bloom", func() filter.Filter { return bloom.NewBloomFilterWithFPR(harnessItems, harnessFPR) }},
+		{"bloom-xxhash", func() filter.Filter {
+			return bloom.NewBloomFilterWithFPR(harnessItems, harnessFPR, bloom.WithHasher(bloom.DefaultXXHasher))
+		}},
+		{"bloom-counting", func() filter.Filter { return bloom.NewCountingBloomFilterWithFPR(harnessItems, harnessFPR) }},
+		{"bloom-scalable", func() filter.Filter { return bloom.NewScalableBloomFilter(harnessItems/8, harnessFPR) }},
+		{"bloom-concurrent", func() filter.Filter { return bloom.NewConcurrentBloomFilterWithFPR(harnessItems, harnessFPR) }},
+		{"cuckoo", func() filter.Filter {
+			return cuckoo.NewCuckooFilterWithFPR(harnessItems, harnessFPR, cuckoo.WithSeed(1))
+		}},
+	}
+}
+
+func TestHarness(t *testing.T) {
+	data := filter.NewDataset(harnessItems, 200000, 1)
+
+	for _, impl := range implementations() {
+		t.Run(impl.name, func(t *testing.T) {
+			r := filter.Evaluate(impl.name, impl.new(), data)
+			t.Log(r)
+
+			if r.Added != harnessItems {
+				t.Fatalf("expected all %d items to be added, got %d", harnessItems, r.Added)
+			}
+			if r.FalseNegatives != 0 {
+				t.Fatalf("expected no false negatives, got %d", r.FalseNegatives)
+			}
+			if r.FPR > harnessFPR*1.5 {
+				t.Fatalf("FPR %.5f is too far above target %.5f", r.FPR, harnessFPR)
+			}
+			if r.BitsPerItem == 0 {
+				t.Fatal("expected bits per item to be reported")
+			}
+		})
+	}
+}
+
+func TestCanDelete(t *testing.T) {
+	for _, impl := range implementations() {
+		f := impl.new()
+		d, ok := filter.CanDelete(f)
+
+		switch impl.name {
+		case "bloom-counting", "cuckoo":
+			if !ok {
+				t.Fatalf("%s: expected deletion support", impl.name)
+			}
+			f.Add([]byte("apple"))
+			if !d.Remove([]byte("apple")) || f.Contains([]byte("apple")) {
+				t.Fatalf("%s: expected apple to be removed", impl.name)
+			}
+		default:
+			if ok {
+				t.Fatalf("%s: expected no deletion support", impl.name)
+			}
+		}
+	}
+}
+
+func TestSharedBinaryErrors(t *testing.T) {
+	decoders := map[string]encoding.BinaryUnmarshaler{
+		"bloom":          new(bloom.BloomFilter),
+		"bloom-scalable": new(bloom.ScalableBloomFilter),
+		"cuckoo":         new(cuckoo.CuckooFilter),
+		"xor8":           new(xor.XorFilter[uint8]),
+	}
+
+	for name, d := range decoders {
+		if err := d.UnmarshalBinary([]byte("not a filter at all, not even close")); !errors.Is(err, filter.ErrInvalidData) {
+			t.Fatalf("%s: expected filter.ErrInvalidData, got %v", name, err)
+		}
+	}
+}
+
+func BenchmarkFilters(b *testing.B) {
+	data := filter.NewDataset(harnessItems, harnessItems, 1)
+
+	for _, impl := range implementations() {
+		b.Run(impl.name+"/add", func(b *testing.B) {
+			f := impl.new()
+			for i := 0; b.Loop(); i++ {
+				f.Add(data.Added[i%len(data.Added)])
+			}
+		})
+
+		b.Run(impl.name+"/contains", func(b *testing.B) {
+			f := impl.new()
+			r := filter.Evaluate(impl.name, f, data)
+			b.ReportMetric(r.FPR, "fpr")
+			b.ReportMetric(r.BitsPerItem, "bits/item")
+
+			b.ResetTimer()
+			for i := 0; b.Loop(); i++ {
+				f.Contains(data.Absent[i%len(data.Absent)])
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"unsafe"
+
+	base "go-helloworld/filter"
+)
+
+// Binary format, all numbers are little endian:
+//
+//	magic "XORF" | version uint8 | fingerprint bits uint8 | reserved uint16 | seed uint64 |
+//	block length uint32 | count uint32 | fingerprints []uint8 or []uint16 | crc32 uint32
+//
+// crc32 (IEEE) covers everything before it.
+const (
+	binaryMagic      = "XORF"
+	binaryVersion    = 1
+	binaryHeaderSize = 24
+	checksumSize     = 4
+)
+
+// fingerprintBytes returns size of one fingerprint
+func fingerprintBytes[T Fingerprint]() int {
+	return int(unsafe.Sizeof(T(0)))
+}
+
+// MarshalBinary encodes the filter
+func (xf *XorFilter[T]) MarshalBinary() ([]byte, error) {
+	size := fingerprintBytes[T]()
+
+	buf := make([]byte, 0, binaryHeaderSize+size*len(xf.fingerprints)+checksumSize)
+	buf = append(buf, binaryMagic...)
+	buf = append(buf, binaryVersion, byte(size*8), 0, 0)
+	buf = binary.LittleEndian.AppendUint64(buf, xf.seed)
+	buf = binary.LittleEndian.AppendUint32(buf, xf.blockLength)
+	buf = binary.LittleEndian.AppendUint32(buf, xf.count)
+	for _, f := range xf.fingerprints {
+		if size == 1 {
+			buf = append(buf, byte(f))
+		} else {
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(f))
+		}
+	}
+	return binary.LittleEndian.AppendUint32(buf, crc32.ChecksumIEEE(buf)), nil
+}
+
+// UnmarshalBinary replaces the filter with the decoded one, the fingerprint width must match T
+func (xf *XorFilter[T]) UnmarshalBinary(data []byte) error {
+	blockLength, err := decodeHeader[T](data)
+	if err != nil {
+		return err
+	}
+
+	size := fingerprintBytes[T]()
+	n := 3 * int(blockLength)
+	if len(data) != binaryHeaderSize+size*n+checksumSize {
+		return base.ErrInvalidData
+	}
+
+	body := data[:len(data)-checksumSize]
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(data[len(body):]) {
+		return base.ErrChecksum
+	}
+
+	fingerprints := make([]T, n)
+	for i := range fingerprints {
+		if size == 1 {
+			fingerprints[i] = T(body[binaryHeaderSize+i])
+		} else {
+			fingerprints[i] = T(binary.LittleEndian.Uint16(body[binaryHeaderSize+2*i:]))
+		}
+	}
+
+	*xf = XorFilter[T]{
+		seed:         binary.LittleEndian.Uint64(body[8:16]),
+		blockLength:  blockLength,
+		count:        binary.LittleEndian.Uint32(body[20:24]),
+		fingerprints: fingerprints,
+	}
+	return nil
+}
+
+// decodeHeader validates the header and returns the block length
+func decodeHeader[T Fingerprint](data []byte) (uint32, error) {
+	if len(data) < binaryHeaderSize || string(data[:4]) != binaryMagic {
+		return 0, base.ErrInvalidData
+	}
+	if data[4] != binaryVersion {
+		return 0, base.ErrVersion
+	}
+	if int(data[5]) != fingerprintBytes[T]()*8 {
+		return 0, base.ErrInvalidData
+	}
+
+	blockLength := binary.LittleEndian.Uint32(data[16:20])
+	if blockLength == 0 || blockLength > 1<<30 {
+		return 0, base.ErrInvalidData
+	}
+	return blockLength, nil
+}
+
+// WriteTo writes the binary encoding of the filter to w
+func (xf *XorFilter[T]) WriteTo(w io.Writer) (int64, error) {
+	data, err := xf.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+	return int64(n), err
+}
+
+// ReadFrom reads one filter from r, it does not read past the end of the filter
+func (xf *XorFilter[T]) ReadFrom(r io.Reader) (int64, error) {
+	header := make([]byte, binaryHeaderSize)
+	n, err := io.ReadFull(r, header)
+	if err != nil {
+		return int64(n), err
+	}
+
+	blockLength, err := decodeHeader[T](header)
+	if err != nil {
+		return int64(n), err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(header)
+	rest, err := io.CopyN(&buf, r, int64(3*int(blockLength)*fingerprintBytes[T]()+checksumSize))
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return int64(n) + rest, err
+	}
+
+	return int64(n) + rest, xf.UnmarshalBinary(buf.Bytes())
+}
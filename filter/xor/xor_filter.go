@@ -0,0 +1,189 @@
+package filter
+
+import (
+	"errors"
+	"math/bits"
+	"slices"
+	"unsafe"
+
+	"go-helloworld/internal/hashing"
+)
+
+// Fingerprint is the fingerprint type, 8-bit fingerprints give about 0.4% false positives, 16-bit ones 0.0015%
+type Fingerprint interface {
+	~uint8 | ~uint16
+}
+
+// maxAttempts bounds number of seeds tried during construction, one attempt fails with a tiny probability
+const maxAttempts = 100
+
+var ErrConstruction = errors.New("filter: xor filter construction failed")
+
+// XorFilter is a static filter built once from a known set of keys, see Graf & Lemire "Xor Filters".
+// It uses about 1.23*bits per key, e.g. 9.84 bits per key with 8-bit fingerprints, which is less than
+// a Bloom filter with the same false positive rate. Keys can not be added after construction.
+type XorFilter[T Fingerprint] struct {
+	seed         uint64
+	blockLength  uint32 // fingerprints has 3 blocks, every key hashes to one slot in every block
+	count        uint32 // number of distinct keys
+	fingerprints []T
+}
+
+// NewXorFilter builds the filter from keys, duplicate keys are allowed
+func NewXorFilter[T Fingerprint](keys [][]byte) (*XorFilter[T], error) {
+	hashes := make([]uint64, len(keys))
+	for i, k := range keys {
+		hashes[i] = hashing.FNV64a(k)
+	}
+	slices.Sort(hashes)
+	hashes = slices.Compact(hashes)
+
+	capacity := 32 + uint32(float64(len(hashes))*1.23+0.5)
+	capacity = capacity / 3 * 3
+
+	xf := &XorFilter[T]{
+		blockLength:  capacity / 3,
+		count:        uint32(len(hashes)),
+		fingerprints: make([]T, capacity),
+	}
+
+	b := newBuilder(capacity)
+	seed := uint64(0x726b2b9d438b9d4d)
+	for range maxAttempts {
+		seed = splitmix64(seed)
+		xf.seed = seed
+
+		if stack, ok := b.peel(seed, xf.blockLength, hashes); ok {
+			xf.assign(stack)
+			return xf, nil
+		}
+	}
+	return nil, ErrConstruction
+}
+
+func splitmix64(seed uint64) uint64 {
+	seed += 0x9e3779b97f4a7c15
+	z := seed
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	return z ^ z>>31
+}
+
+// reduce maps x to [0, n) without division
+func reduce(x uint32, n uint32) uint32 {
+	return uint32(uint64(x) * uint64(n) >> 32)
+}
+
+// slots returns the key slot in every block
+func slots(h uint64, blockLength uint32) [3]uint32 {
+	return [3]uint32{
+		reduce(uint32(h), blockLength),
+		reduce(uint32(bits.RotateLeft64(h, 21)), blockLength) + blockLength,
+		reduce(uint32(bits.RotateLeft64(h, 42)), blockLength) + 2*blockLength,
+	}
+}
+
+func fingerprint[T Fingerprint](h uint64) T {
+	return T(h ^ h>>32)
+}
+
+// Contains checks whether a key is probably present in the filter.
+func (xf *XorFilter[T]) Contains(key []byte) bool {
+	h := hashing.Mix64(hashing.FNV64a(key) + xf.seed)
+	s := slots(h, xf.blockLength)
+	return fingerprint[T](h) == xf.fingerprints[s[0]]^xf.fingerprints[s[1]]^xf.fingerprints[s[2]]
+}
+
+// assign sets fingerprints in the reverse peeling order, so the slot of every key is set after
+// the other two slots of the key got their final values
+func (xf *XorFilter[T]) assign(stack []keyIndex) {
+	for i := len(stack) - 1; i >= 0; i-- {
+		ki := stack[i]
+		s := slots(ki.hash, xf.blockLength)
+
+		f := fingerprint[T](ki.hash)
+		for _, j := range s {
+			if j != ki.index {
+				f ^= xf.fingerprints[j]
+			}
+		}
+		xf.fingerprints[ki.index] = f
+	}
+}
+
+// Count returns number of distinct keys in the filter
+func (xf *XorFilter[T]) Count() int {
+	return int(xf.count)
+}
+
+// SizeBits returns size of the fingerprints in bits
+func (xf *XorFilter[T]) SizeBits() uint64 {
+	return uint64(len(xf.fingerprints)) * uint64(unsafe.Sizeof(T(0))) * 8
+}
+
+// builder keeps the peeling state, it is reused between attempts
+type builder struct {
+	xorMask []uint64 // xor of hashes of keys which use the slot
+	counts  []uint32 // number of keys which use the slot
+	queue   []uint32
+	stack   []keyIndex
+}
+
+// keyIndex is a key hash and the slot assigned to it
+type keyIndex struct {
+	hash  uint64
+	index uint32
+}
+
+func newBuilder(capacity uint32) *builder {
+	return &builder{
+		xorMask: make([]uint64, capacity),
+		counts:  make([]uint32, capacity),
+		queue:   make([]uint32, 0, capacity),
+	}
+}
+
+// peel finds the order of keys in which every key has a slot no key after it uses.
+// It fails if the hypergraph of the keys has a cycle, then another seed is needed.
+func (b *builder) peel(seed uint64, blockLength uint32, hashes []uint64) ([]keyIndex, bool) {
+	clear(b.xorMask)
+	clear(b.counts)
+	b.queue = b.queue[:0]
+	b.stack = make([]keyIndex, 0, len(hashes))
+
+	for _, kh := range hashes {
+		h := hashing.Mix64(kh + seed)
+		for _, i := range slots(h, blockLength) {
+			b.xorMask[i] ^= h
+			b.counts[i]++
+		}
+	}
+
+	for i, c := range b.counts {
+		if c == 1 {
+			b.queue = append(b.queue, uint32(i))
+		}
+	}
+
+	for len(b.queue) > 0 {
+		i := b.queue[len(b.queue)-1]
+		b.queue = b.queue[:len(b.queue)-1]
+		if b.counts[i] != 1 {
+			continue
+		}
+
+		// the only key which uses the slot
+		h := b.xorMask[i]
+		b.stack = append(b.stack, keyIndex{hash: h, index: i})
+
+		for _, j := range slots(h, blockLength) {
+			b.xorMask[j] ^= h
+			b.counts[j]--
+			if b.counts[j] == 1 {
+				b.queue = append(b.queue, j)
+			}
+		}
+	}
+
+	return b.stack, len(b.stack) == len(hashes)
+}
@@ -0,0 +1,167 @@
+package filter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	base "go-helloworld/filter"
+	bloom "go-helloworld/filter/bloom"
+	cuckoo "go-helloworld/filter/cuckoo"
+)
+
+func TestXorFilter(t *testing.T) {
+	data := base.NewDataset(50000, 200000, 1)
+
+	t.Run("8-bit", func(t *testing.T) {
+		testXorFilter[uint8](t, data, 0.006)
+	})
+	t.Run("16-bit", func(t *testing.T) {
+		testXorFilter[uint16](t, data, 0.0001)
+	})
+
+	t.Run("Duplicates and empty set", func(t *testing.T) {
+		keys := [][]byte{[]byte("apple"), []byte("apple"), []byte("banana")}
+		xf, err := NewXorFilter[uint8](keys)
+		if err != nil {
+			t.Fatalf("build: %v", err)
+		}
+		if xf.Count() != 2 || !xf.Contains([]byte("apple")) || !xf.Contains([]byte("banana")) {
+			t.Fatalf("expected 2 keys to be found, count %d", xf.Count())
+		}
+
+		empty, err := NewXorFilter[uint16](nil)
+		if err != nil {
+			t.Fatalf("build empty: %v", err)
+		}
+		if empty.Count() != 0 || empty.Contains([]byte("apple")) {
+			t.Fatal("expected empty filter to contain nothing")
+		}
+	})
+}
+
+func testXorFilter[T Fingerprint](t *testing.T, data base.Dataset, maxFPR float64) {
+	xf, err := NewXorFilter[T](data.Added)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	for _, item := range data.Added {
+		if !xf.Contains(item) {
+			t.Fatalf("false negative for %s", item)
+		}
+	}
+
+	if fpr := falsePositiveRate(xf, data.Absent); fpr > maxFPR {
+		t.Fatalf("FPR %.5f is above %.5f", fpr, maxFPR)
+	}
+	if bpi := float64(xf.SizeBits()) / float64(len(data.Added)); bpi > 1.24*float64(fingerprintBytes[T]()*8) {
+		t.Fatalf("expected about 1.23 fingerprints per key, got %.2f bits per key", bpi)
+	}
+}
+
+func falsePositiveRate(f interface{ Contains([]byte) bool }, absent [][]byte) float64 {
+	n := 0
+	for _, item := range absent {
+		if f.Contains(item) {
+			n++
+		}
+	}
+	return float64(n) / float64(len(absent))
+}
+
+func TestXorFilterBinary(t *testing.T) {
+	keys := base.NewDataset(1000, 0, 2).Added
+	src, err := NewXorFilter[uint16](keys)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := src.WriteTo(&buf); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	data := bytes.Clone(buf.Bytes())
+
+	var dst XorFilter[uint16]
+	if _, err := dst.ReadFrom(&buf); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if dst.Count() != src.Count() {
+		t.Fatalf("expected count %d, got %d", src.Count(), dst.Count())
+	}
+	for _, k := range keys {
+		if !dst.Contains(k) {
+			t.Fatalf("false negative after round trip for %s", k)
+		}
+	}
+
+	corrupted := bytes.Clone(data)
+	corrupted[binaryHeaderSize] ^= 1
+	cases := map[string]struct {
+		data []byte
+		err  error
+	}{
+		"checksum":  {data: corrupted, err: base.ErrChecksum},
+		"truncated": {data: data[:len(data)-1], err: base.ErrInvalidData},
+		"magic":     {data: append([]byte("XXXX"), data[4:]...), err: base.ErrInvalidData},
+	}
+	for name, tc := range cases {
+		if err := dst.UnmarshalBinary(tc.data); !errors.Is(err, tc.err) {
+			t.Fatalf("%s: expected %v, got %v", name, tc.err, err)
+		}
+	}
+
+	var narrow XorFilter[uint8]
+	if err := narrow.UnmarshalBinary(data); !errors.Is(err, base.ErrInvalidData) {
+		t.Fatalf("expected width mismatch to fail, got %v", err)
+	}
+	if _, err := narrow.ReadFrom(bytes.NewReader(data[:10])); err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected unexpected EOF, got %v", err)
+	}
+}
+
+// TestCompareStaticFilter compares size and FPR of the xor filter with Bloom and cuckoo filters
+// sized for about the same false positive rate on the same dataset.
+func TestCompareStaticFilter(t *testing.T) {
+	const n = 50000
+	data := base.NewDataset(n, 200000, 3)
+
+	xor8, err := NewXorFilter[uint8](data.Added)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	xor8FPR := falsePositiveRate(xor8, data.Absent)
+	xor8Bits := float64(xor8.SizeBits()) / n
+	t.Logf("%-20s added=%-7d fpr=%.5f bits/item=%6.2f", "xor8", n, xor8FPR, xor8Bits)
+
+	results := []base.Result{
+		base.Evaluate("bloom", bloom.NewBloomFilterWithFPR(n, 1.0/256), data),
+		base.Evaluate("cuckoo", cuckoo.NewCuckooFilterWithFPR(n, 1.0/256, cuckoo.WithSeed(1)), data),
+	}
+	for _, r := range results {
+		t.Log(r)
+		if r.BitsPerItem <= xor8Bits {
+			t.Fatalf("expected xor8 (%.2f bits/item) to be smaller than %s (%.2f bits/item)", xor8Bits, r.Name, r.BitsPerItem)
+		}
+	}
+}
+
+func BenchmarkXorFilter(b *testing.B) {
+	data := base.NewDataset(100000, 100000, 1)
+
+	b.Run("build", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			NewXorFilter[uint8](data.Added)
+		}
+	})
+
+	b.Run("contains", func(b *testing.B) {
+		xf, _ := NewXorFilter[uint8](data.Added)
+		for i := 0; b.Loop(); i++ {
+			xf.Contains(data.Absent[i%len(data.Absent)])
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package heap
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"sync"
+	"time"
+)
+
+var ErrClosed = errors.New("job queue is closed")
+
+// queuedJob is a job with the time it was pushed
+type queuedJob[T any] struct {
+	job      T
+	priority int
+	enqueued time.Time
+}
+
+// JobQueue is a blocking priority queue for worker pools, lower priority number is more urgent.
+//
+// Jobs are aged, so low priority jobs are not starved: a job is ordered by enqueue time + priority*agingInterval,
+// i.e. every agingInterval of waiting makes it as urgent as a job one priority level higher.
+// With zero agingInterval jobs are ordered by priority only. Jobs with the same key are taken in push order.
+type JobQueue[T any] struct {
+	mu            sync.Mutex
+	pq            *PQ[queuedJob[T]]
+	agingInterval time.Duration
+	lengths       map[int]int // priority -> number of queued jobs
+	now           func() time.Time
+	closed        bool
+
+	// Pop waits on notEmpty, it is closed and replaced to wake the waiters, see bounded.Queue
+	notEmpty chan struct{}
+	waiters  int
+}
+
+func NewJobQueue[T any](agingInterval time.Duration) *JobQueue[T] {
+	q := &JobQueue[T]{
+		agingInterval: agingInterval,
+		lengths:       map[int]int{},
+		now:           time.Now,
+		notEmpty:      make(chan struct{}),
+	}
+	q.pq = NewPQ(q.less, WithStableOrder())
+	return q
+}
+
+// SetClock replaces time.Now, e.g. with a fake clock in tests. It must be called before the queue is used.
+func (q *JobQueue[T]) SetClock(now func() time.Time) {
+	q.now = now
+}
+
+func (q *JobQueue[T]) less(a, b queuedJob[T]) bool {
+	if q.agingInterval == 0 {
+		return a.priority < b.priority
+	}
+	return q.key(a).Before(q.key(b))
+}
+
+// key is the time when the job becomes as urgent as a new job with zero priority
+func (q *JobQueue[T]) key(j queuedJob[T]) time.Time {
+	return j.enqueued.Add(time.Duration(j.priority) * q.agingInterval)
+}
+
+// Push adds the job with the priority, it fails with ErrClosed after Close
+func (q *JobQueue[T]) Push(priority int, job T) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	q.pq.Push(queuedJob[T]{job: job, priority: priority, enqueued: q.now()})
+	q.lengths[priority]++
+
+	if q.waiters > 0 {
+		close(q.notEmpty)
+		q.notEmpty = make(chan struct{})
+	}
+	return nil
+}
+
+// Pop removes the most urgent job, it waits for a job until ctx is done.
+// After Close it returns the remaining jobs, then ErrClosed.
+func (q *JobQueue[T]) Pop(ctx context.Context) (T, error) {
+	for {
+		q.mu.Lock()
+		if qj, ok := q.pq.Pop(); ok {
+			q.lengths[qj.priority]--
+			if q.lengths[qj.priority] == 0 {
+				delete(q.lengths, qj.priority)
+			}
+			q.mu.Unlock()
+			return qj.job, nil
+		}
+		if q.closed {
+			q.mu.Unlock()
+			var zeroVal T
+			return zeroVal, ErrClosed
+		}
+
+		wait := q.notEmpty
+		q.waiters++
+		q.mu.Unlock()
+
+		select {
+		case <-wait:
+			q.mu.Lock()
+			q.waiters--
+			q.mu.Unlock()
+		case <-ctx.Done():
+			q.mu.Lock()
+			q.waiters--
+			q.mu.Unlock()
+			var zeroVal T
+			return zeroVal, ctx.Err()
+		}
+	}
+}
+
+// Close stops accepting jobs and wakes all waiting Pop calls, closing a closed queue does nothing
+func (q *JobQueue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.notEmpty)
+	q.notEmpty = make(chan struct{})
+}
+
+// Len returns number of queued jobs
+func (q *JobQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.pq.Len()
+}
+
+// Lengths returns number of queued jobs per priority, the map is a copy
+func (q *JobQueue[T]) Lengths() map[int]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return maps.Clone(q.lengths)
+}
@@ -0,0 +1,154 @@
+package heap
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// popN pops n jobs which must be in the queue already
+func popN[T any](t *testing.T, q *JobQueue[T], n int) []T {
+	t.Helper()
+
+	jobs := make([]T, 0, n)
+	for range n {
+		job, err := q.Pop(context.Background())
+		if err != nil {
+			t.Fatalf("pop: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+func TestJobQueue(t *testing.T) {
+	t.Run("Priority order", func(t *testing.T) {
+		q := NewJobQueue[string](0)
+		q.Push(3, "c")
+		q.Push(1, "a1")
+		q.Push(2, "b")
+		q.Push(1, "a2")
+
+		if expected := map[int]int{1: 2, 2: 1, 3: 1}; !maps.Equal(q.Lengths(), expected) {
+			t.Fatalf("expected lengths %v, got %v", expected, q.Lengths())
+		}
+
+		if got, expected := popN(t, q, 4), []string{"a1", "a2", "b", "c"}; !slices.Equal(got, expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+		if q.Len() != 0 || len(q.Lengths()) != 0 {
+			t.Fatalf("expected empty queue, got lengths %v", q.Lengths())
+		}
+	})
+
+	t.Run("Aging", func(t *testing.T) {
+		now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		q := NewJobQueue[string](time.Second)
+		q.SetClock(func() time.Time { return now })
+
+		q.Push(5, "old low")
+		now = now.Add(3 * time.Second)
+		q.Push(0, "new high")
+		if got := popN(t, q, 1); got[0] != "new high" {
+			t.Fatalf("expected high priority job to go first after 3s, got %v", got)
+		}
+
+		// after 5 intervals the low priority job is as urgent as a new job with priority 0
+		now = now.Add(3 * time.Second)
+		q.Push(0, "newer high")
+		if got, expected := popN(t, q, 2), []string{"old low", "newer high"}; !slices.Equal(got, expected) {
+			t.Fatalf("expected aged job to go first, got %v", got)
+		}
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		q := NewJobQueue[int](time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if _, err := q.Pop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected pop to time out, got %v", err)
+		}
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		q := NewJobQueue[int](time.Second)
+
+		errs := make(chan error)
+		go func() {
+			_, err := q.Pop(context.Background())
+			errs <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		q.Push(0, 1)
+		if err := <-errs; err != nil {
+			t.Fatalf("expected waiting pop to get the job, got %v", err)
+		}
+
+		go func() {
+			_, err := q.Pop(context.Background())
+			errs <- err
+		}()
+		time.Sleep(10 * time.Millisecond)
+		q.Close()
+		if err := <-errs; !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed, got %v", err)
+		}
+		if err := q.Push(0, 2); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed on push, got %v", err)
+		}
+	})
+
+	t.Run("Close drains", func(t *testing.T) {
+		q := NewJobQueue[int](time.Second)
+		q.Push(1, 1)
+		q.Push(0, 2)
+		q.Close()
+
+		if got := popN(t, q, 2); !slices.Equal(got, []int{2, 1}) {
+			t.Fatalf("expected remaining jobs, got %v", got)
+		}
+		if _, err := q.Pop(context.Background()); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed after drain, got %v", err)
+		}
+	})
+}
+
+func TestJobQueueWorkers(t *testing.T) {
+	const workers, jobs = 4, 2000
+
+	q := NewJobQueue[int](time.Millisecond)
+	var processed atomic.Int64
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				_, err := q.Pop(context.Background())
+				if errors.Is(err, ErrClosed) {
+					return
+				}
+				processed.Add(1)
+			}
+		}()
+	}
+
+	for i := range jobs {
+		if err := q.Push(i%5, i); err != nil {
+			t.Fatalf("push: %v", err)
+		}
+	}
+	q.Close()
+	wg.Wait()
+
+	if processed.Load() != jobs {
+		t.Fatalf("expected %d jobs, got %d", jobs, processed.Load())
+	}
+}
@@ -1,7 +1,6 @@
 package heap
 
 import (
-	"container/heap"
 	"fmt"
 	"sync"
 	"testing"
@@ -14,49 +13,26 @@ type Job struct {
 	Timestamp time.Time
 }
 
-type JobPriorityQueue []*Job
-
-func (pq JobPriorityQueue) Len() int {
-	return len(pq)
-}
-
-func (pq JobPriorityQueue) Less(i, j int) bool {
-	if pq[i].Priority == pq[j].Priority {
-		return pq[i].Timestamp.Before(pq[j].Timestamp)
+// jobLess orders jobs by priority, then by time
+func jobLess(a, b *Job) bool {
+	if a.Priority == b.Priority {
+		return a.Timestamp.Before(b.Timestamp)
 	}
-	return pq[i].Priority < pq[j].Priority
-}
-
-func (pq JobPriorityQueue) Swap(i, j int) {
-	pq[i], pq[j] = pq[j], pq[i]
-}
-
-func (pq *JobPriorityQueue) Push(x interface{}) {
-	item := x.(*Job)
-	*pq = append(*pq, item)
-}
-
-func (pq *JobPriorityQueue) Pop() interface{} {
-	old := *pq
-	n := len(old)
-	lastElem := old[n-1]
-	*pq = old[:n-1]
-	return lastElem
+	return a.Priority < b.Priority
 }
 
 type SafeJobQueue struct {
 	mu       sync.Mutex
-	pq       JobPriorityQueue
+	pq       *PQ[*Job]
 	cond     *sync.Cond
 	isClosed bool
 }
 
 func NewSafeJobQueue() *SafeJobQueue {
 	q := &SafeJobQueue{
-		pq: make(JobPriorityQueue, 0),
+		pq: NewPQ(jobLess, WithStableOrder()),
 	}
 	q.cond = sync.NewCond(&q.mu)
-	heap.Init(&q.pq)
 	return q
 }
 
@@ -68,7 +44,7 @@ func (q *SafeJobQueue) Push(job *Job) {
 		return
 	}
 
-	heap.Push(&q.pq, job)
+	q.pq.Push(job)
 	q.cond.Signal()
 }
 
@@ -85,7 +61,8 @@ func (q *SafeJobQueue) Pop() *Job {
 	}
 
 	fmt.Println("Unblocking job queue")
-	return heap.Pop(&q.pq).(*Job)
+	job, _ := q.pq.Pop()
+	return job
 }
 
 func (q *SafeJobQueue) Close() {
@@ -1,7 +1,6 @@
 package heap
 
 import (
-	"container/heap"
 	"reflect"
 	"testing"
 )
@@ -11,30 +10,9 @@ type WordCount struct {
 	count int
 }
 
-type PriorityHeap []WordCount
-
-func (ph PriorityHeap) Len() int {
-	return len(ph)
-}
-
-func (ph PriorityHeap) Less(i, j int) bool {
-	return ph[i].count < ph[j].count || (ph[i].count == ph[j].count && ph[i].word > ph[j].word)
-}
-
-func (ph PriorityHeap) Swap(i, j int) {
-	ph[i], ph[j] = ph[j], ph[i]
-}
-
-func (ph *PriorityHeap) Push(x interface{}) {
-	*ph = append(*ph, x.(WordCount))
-}
-
-func (ph *PriorityHeap) Pop() interface{} {
-	old := *ph
-	n := len(old)
-	lastElement := old[n-1]
-	*ph = old[:n-1]
-	return lastElement
+// wordLess puts less frequent words first, with equal counts the bigger word is less frequent
+func wordLess(a, b WordCount) bool {
+	return a.count < b.count || (a.count == b.count && a.word > b.word)
 }
 
 func topKFrequent(words []string, k int) []string {
@@ -43,19 +21,19 @@ func topKFrequent(words []string, k int) []string {
 		freq[word]++
 	}
 
-	ph := &PriorityHeap{}
-	heap.Init(ph)
+	pq := NewPQ(wordLess)
 
 	for word, count := range freq {
-		heap.Push(ph, WordCount{word, count})
-		if ph.Len() > k {
-			heap.Pop(ph)
+		pq.Push(WordCount{word, count})
+		if pq.Len() > k {
+			pq.Pop()
 		}
 	}
 
 	result := make([]string, k)
 	for i := k - 1; i >= 0; i-- {
-		result[i] = heap.Pop(ph).(WordCount).word
+		wc, _ := pq.Pop()
+		result[i] = wc.word
 	}
 
 	return result
@@ -0,0 +1,176 @@
+package heap
+
+// Item is a handle of a value in the queue, it is used to update or remove the value
+type Item[T any] struct {
+	value T
+	index int    // position in the heap, -1 after the item left the queue
+	seq   uint64 // insertion order, breaks ties when the queue is stable
+}
+
+// Value returns the value of the item
+func (it *Item[T]) Value() T {
+	return it.value
+}
+
+// Queued reports whether the item is still in the queue
+func (it *Item[T]) Queued() bool {
+	return it.index >= 0
+}
+
+// PQ is a binary heap priority queue, Pop returns the smallest value by the less function.
+// It is not safe for concurrent use.
+type PQ[T any] struct {
+	items  []*Item[T]
+	less   func(a, b T) bool
+	stable bool
+	seq    uint64
+}
+
+// Option configures the queue on creation
+type Option func(*options)
+
+type options struct {
+	stable bool
+}
+
+// WithStableOrder makes equal values leave the queue in the order they were pushed
+func WithStableOrder() Option {
+	return func(o *options) {
+		o.stable = true
+	}
+}
+
+// NewPQ creates an empty queue, less defines the order of values
+func NewPQ[T any](less func(a, b T) bool, opts ...Option) *PQ[T] {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &PQ[T]{less: less, stable: o.stable}
+}
+
+// Len returns number of values in the queue
+func (pq *PQ[T]) Len() int {
+	return len(pq.items)
+}
+
+// Push adds the value and returns its handle
+func (pq *PQ[T]) Push(v T) *Item[T] {
+	it := &Item[T]{value: v, index: len(pq.items), seq: pq.seq}
+	pq.seq++
+
+	pq.items = append(pq.items, it)
+	pq.up(it.index)
+	return it
+}
+
+// Pop removes and returns the smallest value, it returns false if the queue is empty
+func (pq *PQ[T]) Pop() (T, bool) {
+	if len(pq.items) == 0 {
+		var zeroVal T
+		return zeroVal, false
+	}
+	return pq.remove(0), true
+}
+
+// Peek returns the smallest value without removing it
+func (pq *PQ[T]) Peek() (T, bool) {
+	if len(pq.items) == 0 {
+		var zeroVal T
+		return zeroVal, false
+	}
+	return pq.items[0].value, true
+}
+
+// Update replaces the value of the item and restores the order in O(log n).
+// With stable order the item keeps its original insertion order among equal values.
+// It returns false if the item is not in the queue.
+func (pq *PQ[T]) Update(it *Item[T], v T) bool {
+	if !pq.owns(it) {
+		return false
+	}
+
+	it.value = v
+	if !pq.down(it.index, len(pq.items)) {
+		pq.up(it.index)
+	}
+	return true
+}
+
+// Remove removes the item from the queue in O(log n), it returns false if the item is not in the queue
+func (pq *PQ[T]) Remove(it *Item[T]) bool {
+	if !pq.owns(it) {
+		return false
+	}
+	pq.remove(it.index)
+	return true
+}
+
+func (pq *PQ[T]) owns(it *Item[T]) bool {
+	return it.index >= 0 && it.index < len(pq.items) && pq.items[it.index] == it
+}
+
+// remove takes the item at i out of the heap and returns its value
+func (pq *PQ[T]) remove(i int) T {
+	n := len(pq.items) - 1
+	if i != n {
+		pq.swap(i, n)
+		if !pq.down(i, n) {
+			pq.up(i)
+		}
+	}
+
+	it := pq.items[n]
+	pq.items[n] = nil
+	pq.items = pq.items[:n]
+	it.index = -1
+	return it.value
+}
+
+func (pq *PQ[T]) lessAt(i, j int) bool {
+	a, b := pq.items[i], pq.items[j]
+	if pq.less(a.value, b.value) {
+		return true
+	}
+	return pq.stable && a.seq < b.seq && !pq.less(b.value, a.value)
+}
+
+func (pq *PQ[T]) swap(i, j int) {
+	pq.items[i], pq.items[j] = pq.items[j], pq.items[i]
+	pq.items[i].index = i
+	pq.items[j].index = j
+}
+
+// up and down are the same as in container/heap, but without boxing values into interfaces
+
+func (pq *PQ[T]) up(j int) {
+	for j > 0 {
+		i := (j - 1) / 2 // parent
+		if !pq.lessAt(j, i) {
+			break
+		}
+		pq.swap(i, j)
+		j = i
+	}
+}
+
+// down moves the item at i0 down within the first n items and reports whether it moved
+func (pq *PQ[T]) down(i0, n int) bool {
+	i := i0
+	for {
+		j1 := 2*i + 1
+		if j1 >= n || j1 < 0 { // j1 < 0 after int overflow
+			break
+		}
+		j := j1 // left child
+		if j2 := j1 + 1; j2 < n && pq.lessAt(j2, j1) {
+			j = j2 // right child
+		}
+		if !pq.lessAt(j, i) {
+			break
+		}
+		pq.swap(i, j)
+		i = j
+	}
+	return i > i0
+}
@@ -0,0 +1,149 @@
+package heap
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func intLess(a, b int) bool {
+	return a < b
+}
+
+// popAll empties the queue and returns values in the order they were popped
+func popAll[T any](pq *PQ[T]) []T {
+	var values []T
+	for pq.Len() > 0 {
+		v, _ := pq.Pop()
+		values = append(values, v)
+	}
+	return values
+}
+
+func TestPQ(t *testing.T) {
+	t.Run("Sorted order", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(1))
+		values := rnd.Perm(1000)
+
+		pq := NewPQ(intLess)
+		for _, v := range values {
+			pq.Push(v)
+		}
+		if v, ok := pq.Peek(); !ok || v != 0 {
+			t.Fatalf("expected to peek 0, got %d, %v", v, ok)
+		}
+
+		slices.Sort(values)
+		if got := popAll(pq); !slices.Equal(got, values) {
+			t.Fatalf("expected values in sorted order, got %v", got)
+		}
+		if _, ok := pq.Pop(); ok {
+			t.Fatal("expected pop from empty queue to fail")
+		}
+		if _, ok := pq.Peek(); ok {
+			t.Fatal("expected peek into empty queue to fail")
+		}
+	})
+
+	t.Run("Update and Remove", func(t *testing.T) {
+		rnd := rand.New(rand.NewSource(2))
+		pq := NewPQ(intLess)
+
+		// the model is the expected multiset of values in the queue
+		model := map[*Item[int]]int{}
+		for range 2000 {
+			switch op := rnd.Intn(3); {
+			case op == 0 || len(model) == 0:
+				v := rnd.Intn(500)
+				model[pq.Push(v)] = v
+			default:
+				for it := range model {
+					if op == 1 {
+						v := rnd.Intn(500)
+						if !pq.Update(it, v) || it.Value() != v {
+							t.Fatal("expected update to succeed")
+						}
+						model[it] = v
+					} else {
+						if !pq.Remove(it) || it.Queued() {
+							t.Fatal("expected remove to succeed")
+						}
+						delete(model, it)
+					}
+					break
+				}
+			}
+		}
+
+		var expected []int
+		for it, v := range model {
+			if !it.Queued() {
+				t.Fatal("expected item to be queued")
+			}
+			expected = append(expected, v)
+		}
+		slices.Sort(expected)
+		if got := popAll(pq); !slices.Equal(got, expected) {
+			t.Fatalf("expected %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("Stale handle", func(t *testing.T) {
+		pq := NewPQ(intLess)
+		it := pq.Push(1)
+		pq.Push(2)
+		pq.Pop()
+
+		if it.Queued() || pq.Update(it, 0) || pq.Remove(it) {
+			t.Fatal("expected popped item to be rejected")
+		}
+
+		other := NewPQ(intLess)
+		if other.Remove(pq.Push(3)) {
+			t.Fatal("expected item of another queue to be rejected")
+		}
+		if pq.Len() != 2 {
+			t.Fatalf("expected 2 items, got %d", pq.Len())
+		}
+	})
+
+	t.Run("Stable order", func(t *testing.T) {
+		type task struct {
+			priority int
+			name     string
+		}
+		less := func(a, b task) bool { return a.priority < b.priority }
+
+		pq := NewPQ(less, WithStableOrder())
+		var update *Item[task]
+		for i, name := range []string{"a", "b", "c", "d", "e", "f"} {
+			it := pq.Push(task{priority: i % 2, name: name})
+			if name == "c" {
+				update = it
+			}
+		}
+		// c moves to priority 1, but keeps its place between b and d
+		pq.Update(update, task{priority: 1, name: "c"})
+
+		var names []string
+		for _, tk := range popAll(pq) {
+			names = append(names, tk.name)
+		}
+		if expected := []string{"a", "e", "b", "c", "d", "f"}; !slices.Equal(names, expected) {
+			t.Fatalf("expected %v, got %v", expected, names)
+		}
+	})
+}
+
+func BenchmarkPQ(b *testing.B) {
+	pq := NewPQ(intLess)
+	for i := range 1024 {
+		pq.Push(i)
+	}
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		v, _ := pq.Pop()
+		pq.Push(v + 1024)
+	}
+}
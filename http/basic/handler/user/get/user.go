@@ -7,10 +7,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"go-helloworld/cache"
 	"log"
 	"math/rand"
 	"net/http"
-	"sync"
 	"time"
 )
 
@@ -23,73 +23,36 @@ func (e *HTTPError) Error() string {
 	return e.Err.Error()
 }
 
-// test handler with idempotency via singleflight processing
-type call struct {
-	wg  sync.WaitGroup
-	val interface{}
-	err error
-}
-
-type SingleFlight struct {
-	mu sync.Mutex
-	m  map[string]*call
-}
-
-func (s *SingleFlight) Do(key string, fn func() (interface{}, error)) (val interface{}, err error) {
-	s.mu.Lock()
-	if existingCall, exists := s.m[key]; exists {
-		s.mu.Unlock()
-		existingCall.wg.Wait()
-		return existingCall.val, existingCall.err
-	}
-
-	newCall := &call{}
-	newCall.wg.Add(1)
-	s.m[key] = newCall
-	s.mu.Unlock()
-	defer func() {
-		if v := recover(); v != nil {
-			log.Printf("panic recovered in SingleFlight for key=%s: %v", key, v)
-			err = fmt.Errorf("panic: %v", v)
-			newCall.err = err
-			s.mu.Lock()
-			delete(s.m, key)
-			s.mu.Unlock()
-		}
-		newCall.wg.Done()
-	}()
-
-	newCall.val, newCall.err = fn()
-
-	val = newCall.val
-	err = newCall.err
-	return
-}
-
-type CacheEntry struct {
-	Value     []byte
-	UpdatedAt time.Time
-}
+// userIDKey is a context key of the requested user ID. The cache is keyed by the idempotency key only,
+// so the loader gets the user ID of the request from the context, background refreshes keep context values.
+type userIDKey struct{}
 
+// IdempotentUserHandler is a test handler with idempotency via cache.Loading,
+// which dedupes concurrent requests and refreshes stale responses in the background
 type IdempotentUserHandler struct {
-	mu      sync.Mutex
-	cache   map[string]CacheEntry
-	ttl     time.Duration
-	sf      *SingleFlight
+	cache   *cache.Loading[string, []byte]
 	timeout time.Duration
 }
 
 func NewIdempotentHandler(timeout, ttl time.Duration) *IdempotentUserHandler {
 	return &IdempotentUserHandler{
 		timeout: timeout,
-		cache:   make(map[string]CacheEntry),
-		sf: &SingleFlight{
-			m: make(map[string]*call),
-		},
-		ttl: ttl,
+		cache: cache.NewLoading(func(ctx context.Context, key string) ([]byte, error) {
+			// TODO Save to db
+			userID, _ := ctx.Value(userIDKey{}).(string)
+			return task(ctx, userID)
+		}, cache.LoadingOptions{
+			TTL:            ttl,
+			RefreshTimeout: timeout,
+		}),
 	}
 }
 
+// Stats returns cache stats of the handler, so it can be registered in cache.MetricsHandler
+func (i *IdempotentUserHandler) Stats() cache.Stats {
+	return i.cache.Stats()
+}
+
 func (i *IdempotentUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), i.timeout)
 	defer cancel()
@@ -106,56 +69,7 @@ func (i *IdempotentUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		key = generateIdempotencyKey(r)
 	}
 
-	i.mu.Lock()
-	if data, ok := i.cache[key]; ok {
-		if time.Since(data.UpdatedAt) > i.ttl {
-			i.mu.Unlock()
-			// update cache in the background with ctx background to avoid memory leak and control goroutine lifetime
-			go func() {
-				res, err := i.sf.Do(key, func() (interface{}, error) {
-					ctxBackground, ctxBackgroundCancel := context.WithTimeout(context.Background(), i.timeout)
-					defer func() {
-						log.Printf("background refresh done for key=%s", key)
-						ctxBackgroundCancel()
-					}()
-					return task(ctxBackground, userID)
-				})
-				if err != nil {
-					// we need not lose errors and at least keep it somewhere
-					log.Printf("error updating user data in the background %v", err)
-					return
-				}
-
-				data, ok := res.([]byte)
-				if !ok {
-					log.Printf("invalid type in SingleFlight result: expected []byte, got %T (key=%s)", res, key)
-					return
-				}
-
-				// Save to cache
-				i.mu.Lock()
-				i.cache[key] = CacheEntry{
-					Value:     data,
-					UpdatedAt: time.Now(),
-				}
-				i.mu.Unlock()
-
-				// TODO Save to db
-			}()
-			// return cache that will be soon updated
-			w.Header().Set("X-Cache", "STALE")
-			w.Write(data.Value)
-			return
-		}
-		w.Header().Set("X-Cache", "HIT")
-		w.Write(data.Value)
-		return
-	}
-	i.mu.Unlock()
-
-	res, err := i.sf.Do(key, func() (interface{}, error) {
-		return task(ctx, userID)
-	})
+	data, status, err := i.cache.Get(context.WithValue(ctx, userIDKey{}, userID), key)
 	if err != nil {
 		// we need not lose errors and at least keep it somewhere
 		var httpErr *HTTPError
@@ -170,24 +84,7 @@ func (i *IdempotentUserHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	data, ok := res.([]byte)
-	if !ok {
-		errMsg := fmt.Sprintf("invalid type in SingleFlight result: expected []byte, got %T (key=%s)", res, key)
-		log.Printf("error: %s", errMsg)
-		http.Error(w, "interval server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Save to cache
-	i.mu.Lock()
-	i.cache[key] = CacheEntry{
-		Value:     data,
-		UpdatedAt: time.Now(),
-	}
-	i.mu.Unlock()
-
-	// TODO Save to db
-	w.Header().Set("X-Cache", "MISS")
+	w.Header().Set("X-Cache", string(status))
 	w.Write(data)
 }
 
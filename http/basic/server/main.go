@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"go-helloworld/cache"
 	idempotency "go-helloworld/http/basic/handler/user/get"
 	"go-helloworld/http/basic/middleware/httperror"
 	"go-helloworld/http/basic/middleware/recover"
@@ -115,6 +116,11 @@ func main() {
 
 	mux.Handle("/idempotency/user", idempotentUserHandler)
 
+	// Expose cache stats in Prometheus text format
+	metricsHandler := cache.NewMetricsHandler()
+	metricsHandler.Register("idempotency_user", idempotentUserHandler)
+	mux.Handle("/metrics", metricsHandler)
+
 	// Register a handler for "/hello", wrapped with logging middleware.
 	// We explicitly wrap helloHandler with http.HandlerFunc to make it a http.Handler,
 	// since logRequestsMiddleware expects a handler, not just a function.
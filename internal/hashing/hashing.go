@@ -0,0 +1,28 @@
+package hashing
+
+// FNV64a is 64-bit FNV-1a, written inline so it does not allocate like hash/fnv
+func FNV64a(data []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, c := range data {
+		h ^= uint64(c)
+		h *= 1099511628211
+	}
+	return h
+}
+
+// Mix64 is the murmur3 finalizer, it spreads every input bit over the whole result
+func Mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
+
+// Sum64 is FNV-1a followed by the finalizer. FNV mixes high bits poorly for keys which differ
+// only at the end, so callers which take different parts of the hash for different purposes,
+// e.g. a fingerprint and a bucket index, need the finalizer.
+func Sum64(data []byte) uint64 {
+	return Mix64(FNV64a(data))
+}
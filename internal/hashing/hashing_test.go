@@ -0,0 +1,26 @@
+package hashing
+
+import (
+	"hash/fnv"
+	"testing"
+)
+
+func TestFNV64a(t *testing.T) {
+	for _, s := range []string{"", "a", "apple", "the quick brown fox"} {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+		if FNV64a([]byte(s)) != h.Sum64() {
+			t.Fatalf("%q: expected %x, got %x", s, h.Sum64(), FNV64a([]byte(s)))
+		}
+	}
+}
+
+func TestSum64(t *testing.T) {
+	if Sum64([]byte("apple")) != Mix64(FNV64a([]byte("apple"))) {
+		t.Fatal("expected Sum64 to be FNV-1a with the finalizer")
+	}
+	// the finalizer is a bijection with a fixed point at zero
+	if Mix64(0) != 0 || Mix64(1) == 1 {
+		t.Fatal("unexpected finalizer output")
+	}
+}
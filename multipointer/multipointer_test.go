@@ -1,8 +1,8 @@
 package multipointer
 
 import (
-	"container/heap"
 	"fmt"
+	"go-helloworld/heap"
 	"testing"
 )
 
@@ -96,33 +96,14 @@ type Element struct {
 	index int
 }
 
-type MinHeap []Element
-
-func (h MinHeap) Len() int           { return len(h) }
-func (h MinHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
-func (h MinHeap) Less(i, j int) bool { return h[i].val < h[j].val }
-
-func (h *MinHeap) Push(x interface{}) {
-	*h = append(*h, x.(Element))
-}
-
-func (h *MinHeap) Pop() interface{} {
-	old := *h
-	lastElement := old[len(old)-1]
-	*h = old[:len(old)-1]
-	return lastElement
-}
-
 func smallestRange(nums [][]int) []int {
-	h := &MinHeap{}
-
-	heap.Init(h)
+	h := heap.NewPQ(func(a, b Element) bool { return a.val < b.val })
 
 	currMax := 0
 
 	for row, arr := range nums {
 		val := arr[0]
-		heap.Push(h, Element{val, row, 0})
+		h.Push(Element{val, row, 0})
 		if val > currMax {
 			currMax = val
 		}
@@ -131,7 +112,7 @@ func smallestRange(nums [][]int) []int {
 	bestStart, bestEnd := int(-1e9), int(1e9)
 
 	for {
-		minElement := heap.Pop(h).(Element)
+		minElement, _ := h.Pop()
 		currMin := minElement.val
 
 		if currMax-currMin < bestEnd-bestStart {
@@ -145,7 +126,7 @@ func smallestRange(nums [][]int) []int {
 		nextIndex := minElement.index + 1
 		nextVal := nums[minElement.arr][nextIndex]
 
-		heap.Push(h, Element{nextVal, minElement.arr, nextIndex})
+		h.Push(Element{nextVal, minElement.arr, nextIndex})
 
 		if nextVal > currMax {
 			currMax = nextVal
@@ -0,0 +1,197 @@
+package bounded
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrClosed = errors.New("queue is closed")
+	ErrEmpty  = errors.New("queue is empty")
+	ErrFull   = errors.New("queue is full")
+)
+
+// Queue is a FIFO queue with fixed capacity. Put blocks while the queue is full and Take blocks while it is empty,
+// both can be cancelled with the context. After Close, Put fails and Take returns the remaining items,
+// then ErrClosed, so workers can stop when the queue is drained.
+type Queue[T any] struct {
+	mu     sync.Mutex
+	items  []T // ring buffer
+	head   int // index of the first item
+	count  int
+	closed bool
+
+	// waiters block on these channels, they are closed and replaced to wake all of them,
+	// which unlike sync.Cond lets a waiter also select on ctx.Done()
+	notEmpty    chan struct{}
+	notFull     chan struct{}
+	takeWaiters int
+	putWaiters  int
+}
+
+func NewQueue[T any](capacity int) *Queue[T] {
+	if capacity <= 0 {
+		panic("queue capacity must be positive")
+	}
+	return &Queue[T]{
+		items:    make([]T, capacity),
+		notEmpty: make(chan struct{}),
+		notFull:  make(chan struct{}),
+	}
+}
+
+// Put adds v to the queue, it waits for free space until ctx is done
+func (q *Queue[T]) Put(ctx context.Context, v T) error {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return ErrClosed
+		}
+		if q.count < len(q.items) {
+			q.push(v)
+			q.mu.Unlock()
+			return nil
+		}
+
+		wait := q.notFull
+		q.putWaiters++
+		q.mu.Unlock()
+
+		select {
+		case <-wait:
+			q.mu.Lock()
+			q.putWaiters--
+			q.mu.Unlock()
+		case <-ctx.Done():
+			q.mu.Lock()
+			q.putWaiters--
+			q.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+}
+
+// Take removes the first item, it waits for an item until ctx is done.
+// It returns ErrClosed when the queue is closed and empty.
+func (q *Queue[T]) Take(ctx context.Context) (T, error) {
+	for {
+		q.mu.Lock()
+		if q.count > 0 {
+			v := q.pop()
+			q.mu.Unlock()
+			return v, nil
+		}
+		if q.closed {
+			q.mu.Unlock()
+			var zeroVal T
+			return zeroVal, ErrClosed
+		}
+
+		wait := q.notEmpty
+		q.takeWaiters++
+		q.mu.Unlock()
+
+		select {
+		case <-wait:
+			q.mu.Lock()
+			q.takeWaiters--
+			q.mu.Unlock()
+		case <-ctx.Done():
+			q.mu.Lock()
+			q.takeWaiters--
+			q.mu.Unlock()
+			var zeroVal T
+			return zeroVal, ctx.Err()
+		}
+	}
+}
+
+// TryPut adds v without waiting, it returns ErrFull if there is no free space
+func (q *Queue[T]) TryPut(v T) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return ErrClosed
+	}
+	if q.count == len(q.items) {
+		return ErrFull
+	}
+	q.push(v)
+	return nil
+}
+
+// TryTake removes the first item without waiting, it returns ErrEmpty if there are no items,
+// or ErrClosed if the queue is closed and drained
+func (q *Queue[T]) TryTake() (T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.count == 0 {
+		var zeroVal T
+		if q.closed {
+			return zeroVal, ErrClosed
+		}
+		return zeroVal, ErrEmpty
+	}
+	return q.pop(), nil
+}
+
+// Close stops accepting new items and wakes all waiters, items which are already in the queue can still be taken.
+// Closing a closed queue does nothing.
+func (q *Queue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return
+	}
+	q.closed = true
+	q.notEmpty = wake(q.notEmpty)
+	q.notFull = wake(q.notFull)
+}
+
+// Len returns number of items in the queue
+func (q *Queue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.count
+}
+
+// Cap returns capacity of the queue
+func (q *Queue[T]) Cap() int {
+	return len(q.items)
+}
+
+// push must be called with the lock held and free space in the queue
+func (q *Queue[T]) push(v T) {
+	q.items[(q.head+q.count)%len(q.items)] = v
+	q.count++
+
+	if q.takeWaiters > 0 {
+		q.notEmpty = wake(q.notEmpty)
+	}
+}
+
+// pop must be called with the lock held and at least one item in the queue
+func (q *Queue[T]) pop() T {
+	var zeroVal T
+	v := q.items[q.head]
+	q.items[q.head] = zeroVal // do not keep a reference to the taken item
+	q.head = (q.head + 1) % len(q.items)
+	q.count--
+
+	if q.putWaiters > 0 {
+		q.notFull = wake(q.notFull)
+	}
+	return v
+}
+
+// wake closes the channel to wake all goroutines waiting on it and returns a new one for the next waiters
+func wake(ch chan struct{}) chan struct{} {
+	close(ch)
+	return make(chan struct{})
+}
@@ -0,0 +1,200 @@
+package bounded
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("FIFO order", func(t *testing.T) {
+		q := NewQueue[int](3)
+		// several rounds, so the ring buffer wraps around
+		for round := range 3 {
+			for i := range 3 {
+				if err := q.Put(ctx, round*10+i); err != nil {
+					t.Fatalf("put: %v", err)
+				}
+			}
+			for i := range 3 {
+				v, err := q.Take(ctx)
+				if err != nil || v != round*10+i {
+					t.Fatalf("expected %d, got %d, %v", round*10+i, v, err)
+				}
+			}
+		}
+	})
+
+	t.Run("Try", func(t *testing.T) {
+		q := NewQueue[int](2)
+		if _, err := q.TryTake(); !errors.Is(err, ErrEmpty) {
+			t.Fatalf("expected ErrEmpty, got %v", err)
+		}
+		q.TryPut(1)
+		q.TryPut(2)
+		if err := q.TryPut(3); !errors.Is(err, ErrFull) {
+			t.Fatalf("expected ErrFull, got %v", err)
+		}
+		if q.Len() != 2 || q.Cap() != 2 {
+			t.Fatalf("expected len 2 and cap 2, got %d and %d", q.Len(), q.Cap())
+		}
+		if v, err := q.TryTake(); err != nil || v != 1 {
+			t.Fatalf("expected 1, got %d, %v", v, err)
+		}
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		q := NewQueue[int](1)
+		q.TryPut(1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		if err := q.Put(ctx, 2); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected put to time out, got %v", err)
+		}
+
+		q.TryTake()
+		if _, err := q.Take(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("expected take to time out, got %v", err)
+		}
+	})
+
+	t.Run("Blocked put wakes up", func(t *testing.T) {
+		q := NewQueue[int](1)
+		q.TryPut(1)
+
+		done := make(chan error)
+		go func() {
+			done <- q.Put(ctx, 2)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		if v, _ := q.Take(ctx); v != 1 {
+			t.Fatalf("expected 1, got %d", v)
+		}
+		if err := <-done; err != nil {
+			t.Fatalf("put: %v", err)
+		}
+		if v, _ := q.Take(ctx); v != 2 {
+			t.Fatalf("expected 2, got %d", v)
+		}
+	})
+
+	t.Run("Close drains", func(t *testing.T) {
+		q := NewQueue[int](3)
+		q.TryPut(1)
+		q.TryPut(2)
+		q.Close()
+		q.Close()
+
+		if err := q.Put(ctx, 3); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed on put, got %v", err)
+		}
+		if err := q.TryPut(3); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed on try put, got %v", err)
+		}
+		for _, expected := range []int{1, 2} {
+			if v, err := q.Take(ctx); err != nil || v != expected {
+				t.Fatalf("expected %d, got %d, %v", expected, v, err)
+			}
+		}
+		if _, err := q.Take(ctx); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed on take, got %v", err)
+		}
+		if _, err := q.TryTake(); !errors.Is(err, ErrClosed) {
+			t.Fatalf("expected ErrClosed on try take, got %v", err)
+		}
+	})
+
+	t.Run("Close wakes waiters", func(t *testing.T) {
+		empty, full := NewQueue[int](1), NewQueue[int](1)
+		full.TryPut(1)
+
+		errs := make(chan error, 2)
+		go func() {
+			_, err := empty.Take(ctx)
+			errs <- err
+		}()
+		go func() {
+			errs <- full.Put(ctx, 2)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		empty.Close()
+		full.Close()
+		for range 2 {
+			if err := <-errs; !errors.Is(err, ErrClosed) {
+				t.Fatalf("expected ErrClosed, got %v", err)
+			}
+		}
+	})
+}
+
+// TestWorkerPool runs producers and workers on one small queue, workers stop when the queue is closed and drained
+func TestWorkerPool(t *testing.T) {
+	const producers, workers, jobsPerProducer = 4, 8, 1000
+
+	q := NewQueue[int](16)
+	ctx := context.Background()
+
+	var sum, processed atomic.Int64
+	var workersWg sync.WaitGroup
+	for range workers {
+		workersWg.Add(1)
+		go func() {
+			defer workersWg.Done()
+			for {
+				job, err := q.Take(ctx)
+				if errors.Is(err, ErrClosed) {
+					return
+				}
+				if err != nil {
+					t.Errorf("take: %v", err)
+					return
+				}
+				sum.Add(int64(job))
+				processed.Add(1)
+			}
+		}()
+	}
+
+	var producersWg sync.WaitGroup
+	for p := range producers {
+		producersWg.Add(1)
+		go func() {
+			defer producersWg.Done()
+			for i := range jobsPerProducer {
+				if err := q.Put(ctx, p*jobsPerProducer+i); err != nil {
+					t.Errorf("put: %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	producersWg.Wait()
+	q.Close()
+	workersWg.Wait()
+
+	n := int64(producers * jobsPerProducer)
+	if processed.Load() != n || sum.Load() != n*(n-1)/2 {
+		t.Fatalf("expected %d jobs with sum %d, got %d jobs with sum %d", n, n*(n-1)/2, processed.Load(), sum.Load())
+	}
+}
+
+func BenchmarkQueue(b *testing.B) {
+	q := NewQueue[int](1024)
+	ctx := context.Background()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			q.Put(ctx, 1)
+			q.Take(ctx)
+		}
+	})
+}
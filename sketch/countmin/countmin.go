@@ -0,0 +1,127 @@
+package sketch
+
+import (
+	"errors"
+	"math"
+
+	"go-helloworld/internal/hashing"
+)
+
+var ErrIncompatible = errors.New("sketch: count-min sketches have different dimensions")
+
+// CountMin estimates item frequencies in a stream with depth rows of width counters.
+// Every item increments one counter in every row and the estimate is the smallest of them,
+// so it is never below the true count and is above it by at most epsilon*Total with probability 1-delta.
+type CountMin struct {
+	width    uint64
+	depth    uint64
+	counters []uint64 // depth rows one after another
+	total    uint64   // sum of all added counts
+}
+
+// NewCountMin creates sketch with depth rows of width counters
+func NewCountMin(width, depth int) *CountMin {
+	if width <= 0 || depth <= 0 {
+		panic("sketch: count-min width and depth must be positive")
+	}
+	return &CountMin{
+		width:    uint64(width),
+		depth:    uint64(depth),
+		counters: make([]uint64, width*depth),
+	}
+}
+
+// NewCountMinWithError creates sketch which overestimates by at most epsilon*Total with probability 1-delta.
+// Both epsilon and delta must be in (0, 1).
+func NewCountMinWithError(epsilon, delta float64) *CountMin {
+	if epsilon <= 0 || epsilon >= 1 || delta <= 0 || delta >= 1 {
+		panic("sketch: epsilon and delta must be in (0, 1)")
+	}
+
+	width := int(math.Ceil(math.E / epsilon))
+	depth := int(math.Ceil(math.Log(1 / delta)))
+	return NewCountMin(width, max(depth, 1))
+}
+
+// location returns counter index of the item in the row, rows use double hashing h1 + row*h2
+func (s *CountMin) location(h1, h2, row uint64) uint64 {
+	return row*s.width + (h1+row*h2)%s.width
+}
+
+// Add adds n occurrences of the item and returns its new estimate
+func (s *CountMin) Add(item []byte, n uint64) uint64 {
+	return s.AddHash(hashing.Sum64(item), n)
+}
+
+// AddHash adds n occurrences of an item by its 64-bit hash, the hash bits must be uniformly distributed
+func (s *CountMin) AddHash(h uint64, n uint64) uint64 {
+	h1, h2 := h, h>>32|1
+
+	estimate := uint64(math.MaxUint64)
+	for row := range s.depth {
+		i := s.location(h1, h2, row)
+		s.counters[i] += n
+		estimate = min(estimate, s.counters[i])
+	}
+	s.total += n
+	return estimate
+}
+
+// Estimate returns the estimated count of the item, it is never below the true count
+func (s *CountMin) Estimate(item []byte) uint64 {
+	return s.EstimateHash(hashing.Sum64(item))
+}
+
+// EstimateHash returns the estimated count of an item by its 64-bit hash
+func (s *CountMin) EstimateHash(h uint64) uint64 {
+	h1, h2 := h, h>>32|1
+
+	estimate := uint64(math.MaxUint64)
+	for row := range s.depth {
+		estimate = min(estimate, s.counters[s.location(h1, h2, row)])
+	}
+	return estimate
+}
+
+// Total returns sum of all added counts
+func (s *CountMin) Total() uint64 {
+	return s.total
+}
+
+// Width returns number of counters in a row
+func (s *CountMin) Width() int {
+	return int(s.width)
+}
+
+// Depth returns number of rows
+func (s *CountMin) Depth() int {
+	return int(s.depth)
+}
+
+// Merge adds counts of other to the sketch, as if both streams were added to it.
+// Sketches must have the same width and depth.
+func (s *CountMin) Merge(other *CountMin) error {
+	if s.width != other.width || s.depth != other.depth {
+		return ErrIncompatible
+	}
+
+	for i, c := range other.counters {
+		s.counters[i] += c
+	}
+	s.total += other.total
+	return nil
+}
+
+// Halve divides all counters by two, so old counts fade away, e.g. when frequencies must follow recent traffic
+func (s *CountMin) Halve() {
+	for i := range s.counters {
+		s.counters[i] /= 2
+	}
+	s.total /= 2
+}
+
+// Reset sets all counters to zero
+func (s *CountMin) Reset() {
+	clear(s.counters)
+	s.total = 0
+}
@@ -0,0 +1,166 @@
+package sketch
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// zipfStream returns n words with zipf distributed frequencies and their exact counts
+func zipfStream(n int, seed int64) ([][]byte, map[string]uint64) {
+	rnd := rand.New(rand.NewSource(seed))
+	zipf := rand.NewZipf(rnd, 1.2, 1, 100000)
+
+	words := make([][]byte, n)
+	exact := map[string]uint64{}
+	for i := range words {
+		words[i] = fmt.Appendf(nil, "word-%d", zipf.Uint64())
+		exact[string(words[i])]++
+	}
+	return words, exact
+}
+
+func TestCountMin(t *testing.T) {
+	const epsilon, delta = 0.001, 0.01
+	words, exact := zipfStream(200000, 1)
+
+	s := NewCountMinWithError(epsilon, delta)
+	if s.Width() != 2719 || s.Depth() != 5 {
+		t.Fatalf("expected 2719x5 sketch, got %dx%d", s.Width(), s.Depth())
+	}
+	for _, w := range words {
+		s.Add(w, 1)
+	}
+	if s.Total() != uint64(len(words)) {
+		t.Fatalf("expected total %d, got %d", len(words), s.Total())
+	}
+
+	bound := uint64(epsilon * float64(s.Total()))
+	over := 0
+	for w, count := range exact {
+		estimate := s.Estimate([]byte(w))
+		if estimate < count {
+			t.Fatalf("%s: estimate %d is below the true count %d", w, estimate, count)
+		}
+		if estimate-count > bound {
+			over++
+		}
+	}
+	if rate := float64(over) / float64(len(exact)); rate > delta {
+		t.Fatalf("%.4f of estimates are off by more than %d, expected at most %.2f", rate, bound, delta)
+	}
+
+	before := s.Estimate(words[0])
+	s.Halve()
+	if got := s.Estimate(words[0]); got != before/2 || s.Total() != uint64(len(words))/2 {
+		t.Fatalf("expected halved estimate %d and total %d, got %d and %d", before/2, len(words)/2, got, s.Total())
+	}
+
+	s.Reset()
+	if s.Total() != 0 || s.Estimate(words[0]) != 0 {
+		t.Fatal("expected reset sketch to be empty")
+	}
+}
+
+func TestCountMinMerge(t *testing.T) {
+	words, _ := zipfStream(20000, 2)
+
+	a, b, all := NewCountMin(1000, 4), NewCountMin(1000, 4), NewCountMin(1000, 4)
+	for i, w := range words {
+		if i%2 == 0 {
+			a.Add(w, 1)
+		} else {
+			b.Add(w, 1)
+		}
+		all.Add(w, 1)
+	}
+
+	if err := a.Merge(b); err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if !reflect.DeepEqual(a, all) {
+		t.Fatal("expected merged sketch to equal the sketch of the whole stream")
+	}
+
+	if err := a.Merge(NewCountMin(1000, 5)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible, got %v", err)
+	}
+}
+
+func TestTopK(t *testing.T) {
+	t.Run("Small streams", func(t *testing.T) {
+		// the same cases as heap.TestTopKFrequent, counts are exact in a wide sketch
+		tests := []struct {
+			words    []string
+			k        int
+			expected []string
+		}{
+			{
+				words:    []string{"i", "love", "leetcode", "i", "love", "coding"},
+				k:        2,
+				expected: []string{"i", "love"},
+			},
+			{
+				words:    []string{"the", "day", "is", "sunny", "the", "the", "the", "sunny", "is", "is"},
+				k:        4,
+				expected: []string{"the", "is", "sunny", "day"},
+			},
+		}
+
+		for _, test := range tests {
+			tk := NewTopK(test.k, NewCountMin(1024, 4))
+			for _, w := range test.words {
+				tk.Add([]byte(w))
+			}
+
+			var result []string
+			for _, item := range tk.List() {
+				result = append(result, item.Key)
+			}
+			if !reflect.DeepEqual(result, test.expected) {
+				t.Errorf("For input %v and k=%d, expected %v but got %v", test.words, test.k, test.expected, result)
+			}
+		}
+	})
+
+	t.Run("Zipf stream", func(t *testing.T) {
+		const k = 10
+		words, exact := zipfStream(200000, 3)
+
+		tk := NewTopK(k, NewCountMinWithError(0.001, 0.01))
+		for _, w := range words {
+			tk.Add(w)
+		}
+
+		// with zipf distribution the top words are far apart, so the sketch finds them exactly
+		expected := make([]string, 0, k)
+		for i := range k {
+			expected = append(expected, fmt.Sprintf("word-%d", i))
+		}
+
+		list := tk.List()
+		if len(list) != k {
+			t.Fatalf("expected %d items, got %d", k, len(list))
+		}
+		for i, item := range list {
+			if item.Key != expected[i] {
+				t.Fatalf("expected %v at position %d, got %v", expected[i], i, list)
+			}
+			if item.Count < exact[item.Key] {
+				t.Fatalf("%s: count %d is below the true count %d", item.Key, item.Count, exact[item.Key])
+			}
+		}
+	})
+}
+
+func BenchmarkTopK(b *testing.B) {
+	words, _ := zipfStream(1<<16, 4)
+
+	tk := NewTopK(100, NewCountMinWithError(0.001, 0.01))
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		tk.Add(words[i&(len(words)-1)])
+	}
+}
@@ -0,0 +1,109 @@
+package sketch
+
+import (
+	"cmp"
+	"container/heap"
+	"slices"
+)
+
+// Item is a tracked item with its estimated count
+type Item struct {
+	Key   string
+	Count uint64
+}
+
+// TopK tracks the k most frequent items of a stream in O(k) memory besides the sketch.
+// Counts come from the count-min sketch, the heap keeps k items with the biggest estimates
+// and its root is the least frequent of them, so a new item only has to beat the root.
+type TopK struct {
+	k      int
+	sketch *CountMin
+	items  itemHeap
+	index  map[string]int // key -> position in items
+}
+
+// NewTopK creates top-k tracker on the sketch, the sketch may already have counts
+func NewTopK(k int, sketch *CountMin) *TopK {
+	tk := &TopK{
+		k:      k,
+		sketch: sketch,
+		index:  make(map[string]int, k),
+	}
+	tk.items.index = tk.index
+	return tk
+}
+
+// Add counts one occurrence of the item
+func (tk *TopK) Add(item []byte) {
+	count := tk.sketch.Add(item, 1)
+
+	if i, ok := tk.index[string(item)]; ok {
+		tk.items.entries[i].Count = count
+		heap.Fix(&tk.items, i)
+		return
+	}
+
+	if tk.items.Len() < tk.k {
+		heap.Push(&tk.items, Item{Key: string(item), Count: count})
+		return
+	}
+
+	if tk.k > 0 && less(tk.items.entries[0], Item{Key: string(item), Count: count}) {
+		delete(tk.index, tk.items.entries[0].Key)
+		tk.items.entries[0] = Item{Key: string(item), Count: count}
+		tk.index[string(item)] = 0
+		heap.Fix(&tk.items, 0)
+	}
+}
+
+// List returns tracked items from the most frequent one, ties are ordered by key
+func (tk *TopK) List() []Item {
+	items := slices.Clone(tk.items.entries)
+	slices.SortFunc(items, func(a, b Item) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return items
+}
+
+// less orders items by count, with equal counts the bigger key is less frequent,
+// so the smaller key stays in the top
+func less(a, b Item) bool {
+	return a.Count < b.Count || (a.Count == b.Count && a.Key > b.Key)
+}
+
+// itemHeap is a min-heap of items which keeps the key index up to date
+type itemHeap struct {
+	entries []Item
+	index   map[string]int
+}
+
+func (h itemHeap) Len() int {
+	return len(h.entries)
+}
+
+func (h itemHeap) Less(i, j int) bool {
+	return less(h.entries[i], h.entries[j])
+}
+
+func (h itemHeap) Swap(i, j int) {
+	h.entries[i], h.entries[j] = h.entries[j], h.entries[i]
+	h.index[h.entries[i].Key] = i
+	h.index[h.entries[j].Key] = j
+}
+
+func (h *itemHeap) Push(x any) {
+	item := x.(Item)
+	h.index[item.Key] = len(h.entries)
+	h.entries = append(h.entries, item)
+}
+
+func (h *itemHeap) Pop() any {
+	n := len(h.entries)
+	item := h.entries[n-1]
+	h.entries = h.entries[:n-1]
+	delete(h.index, item.Key)
+	return item
+}
@@ -0,0 +1,234 @@
+package sketch
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"slices"
+
+	"go-helloworld/internal/hashing"
+)
+
+const (
+	minPrecision    = 4
+	maxPrecision    = 18
+	sparsePrecision = 25 // sparse entries keep 25 index bits, so small sets are counted almost exactly
+	sparseBufferLen = 256
+)
+
+var ErrPrecision = errors.New("sketch: hyperloglog precisions differ")
+
+// HyperLogLog estimates the number of distinct items with 2^p registers of one byte,
+// the standard error is about 1.04/sqrt(2^p), e.g. 0.8% for p = 14.
+//
+// A new sketch is sparse: it stores only the registers which were set, with a higher precision,
+// as a sorted list of entries. When the list would take more memory than the registers,
+// the sketch converts itself to the dense form. See Heule et al. "HyperLogLog in Practice".
+type HyperLogLog struct {
+	p         uint8
+	registers []uint8  // dense registers, nil while the sketch is sparse
+	sparse    []uint32 // sorted sparse entries, one per index
+	buffer    []uint32 // unsorted sparse entries which are not merged into sparse yet
+}
+
+// NewHyperLogLog creates an empty sparse sketch, precision must be in [4, 18]
+func NewHyperLogLog(precision uint8) *HyperLogLog {
+	if precision < minPrecision || precision > maxPrecision {
+		panic("sketch: hyperloglog precision must be in [4, 18]")
+	}
+	return &HyperLogLog{p: precision}
+}
+
+// Precision returns number of index bits
+func (h *HyperLogLog) Precision() uint8 {
+	return h.p
+}
+
+// IsSparse reports whether the sketch still uses the sparse form
+func (h *HyperLogLog) IsSparse() bool {
+	return h.registers == nil
+}
+
+// Add adds the item
+func (h *HyperLogLog) Add(item []byte) {
+	h.AddHash(hashing.Sum64(item))
+}
+
+// AddHash adds an item by its 64-bit hash, the hash bits must be uniformly distributed
+func (h *HyperLogLog) AddHash(x uint64) {
+	if h.registers != nil {
+		index, rank := denseEntry(x, h.p)
+		h.registers[index] = max(h.registers[index], rank)
+		return
+	}
+
+	h.buffer = append(h.buffer, sparseEntry(x))
+	if len(h.buffer) >= sparseBufferLen {
+		h.flush()
+	}
+}
+
+// denseEntry returns register index from the top p bits and rank, the position of the first one bit in the rest
+func denseEntry(x uint64, p uint8) (uint32, uint8) {
+	index := uint32(x >> (64 - p))
+	rank := uint8(bits.LeadingZeros64(x<<p|1<<(p-1)) + 1)
+	return index, rank
+}
+
+// sparseEntry packs index with sparsePrecision bits and rank as index<<6 | rank
+func sparseEntry(x uint64) uint32 {
+	index, rank := denseEntry(x, sparsePrecision)
+	return index<<6 | uint32(rank)
+}
+
+// sparseToDense converts a sparse entry to the register index and rank for precision p.
+// The index bits below the top p ones are the start of the dense rank, if they are all zero
+// the dense rank continues with the sparse rank.
+func sparseToDense(e uint32, p uint8) (uint32, uint8) {
+	index, rank := e>>6, uint8(e&0x3f)
+
+	extra := sparsePrecision - p
+	rest := index & (1<<extra - 1)
+	if rest == 0 {
+		return index >> extra, rank + extra
+	}
+	return index >> extra, uint8(bits.LeadingZeros32(rest)-(32-int(extra))) + 1
+}
+
+// flush merges the buffer into the sorted sparse list and switches to the dense form if the list got too big
+func (h *HyperLogLog) flush() {
+	if len(h.buffer) == 0 {
+		return
+	}
+
+	slices.Sort(h.buffer)
+	h.sparse = mergeSparse(h.sparse, h.buffer)
+	h.buffer = h.buffer[:0]
+
+	// registers take 2^p bytes, every sparse entry takes 4
+	if len(h.sparse)*4 > 1<<h.p {
+		h.toDense()
+	}
+}
+
+// mergeSparse merges two sorted entry lists, only the biggest rank of every index is kept
+func mergeSparse(a, b []uint32) []uint32 {
+	merged := make([]uint32, 0, len(a)+len(b))
+	push := func(e uint32) {
+		if n := len(merged); n > 0 && merged[n-1]>>6 == e>>6 {
+			// entries are sorted, so the later one of the same index has the bigger rank
+			merged[n-1] = e
+			return
+		}
+		merged = append(merged, e)
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] <= b[j] {
+			push(a[i])
+			i++
+		} else {
+			push(b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		push(a[i])
+	}
+	for ; j < len(b); j++ {
+		push(b[j])
+	}
+	return merged
+}
+
+func (h *HyperLogLog) toDense() {
+	h.registers = make([]uint8, 1<<h.p)
+	for _, e := range h.sparse {
+		index, rank := sparseToDense(e, h.p)
+		h.registers[index] = max(h.registers[index], rank)
+	}
+	for _, e := range h.buffer {
+		index, rank := sparseToDense(e, h.p)
+		h.registers[index] = max(h.registers[index], rank)
+	}
+	h.sparse, h.buffer = nil, nil
+}
+
+// Count returns the estimated number of distinct items
+func (h *HyperLogLog) Count() uint64 {
+	if h.registers == nil {
+		h.flush()
+	}
+	if h.registers == nil {
+		// linear counting over 2^25 sparse registers, it is exact enough for any set which fits the sparse form
+		return uint64(math.Round(linearCounting(1<<sparsePrecision, 1<<sparsePrecision-len(h.sparse))))
+	}
+
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha(m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = linearCounting(m, zeros)
+	}
+	return uint64(math.Round(estimate))
+}
+
+func linearCounting(m float64, zeros int) float64 {
+	return m * math.Log(m/float64(zeros))
+}
+
+func alpha(m float64) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	}
+	return 0.7213 / (1 + 1.079/m)
+}
+
+// Merge adds all items of other to the sketch, precisions must be equal
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if h.p != other.p {
+		return ErrPrecision
+	}
+
+	if other.registers == nil {
+		other.flush()
+	}
+	if h.registers == nil && other.registers == nil {
+		h.buffer = append(h.buffer, other.sparse...)
+		h.flush()
+		return nil
+	}
+
+	if h.registers == nil {
+		h.toDense()
+	}
+	if other.registers == nil {
+		for _, e := range other.sparse {
+			index, rank := sparseToDense(e, h.p)
+			h.registers[index] = max(h.registers[index], rank)
+		}
+		return nil
+	}
+	for i, r := range other.registers {
+		h.registers[i] = max(h.registers[i], r)
+	}
+	return nil
+}
+
+// Reset removes all items, the sketch becomes sparse again
+func (h *HyperLogLog) Reset() {
+	*h = HyperLogLog{p: h.p}
+}
@@ -0,0 +1,145 @@
+package sketch
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func item(prefix string, i int) []byte {
+	return fmt.Appendf(nil, "%s-%d", prefix, i)
+}
+
+// relativeError returns |estimate - n| / n
+func relativeError(estimate uint64, n int) float64 {
+	return math.Abs(float64(estimate)-float64(n)) / float64(n)
+}
+
+func TestHyperLogLog(t *testing.T) {
+	const p = 14
+	// three standard errors
+	bound := 3 * 1.04 / math.Sqrt(1<<p)
+
+	t.Run("Accuracy", func(t *testing.T) {
+		for _, n := range []int{10, 1000, 5000, 100000, 1000000} {
+			h := NewHyperLogLog(p)
+			for i := range n {
+				h.Add(item("user", i))
+			}
+
+			if e := relativeError(h.Count(), n); e > bound {
+				t.Fatalf("n=%d: estimate %d, error %.4f is above %.4f", n, h.Count(), e, bound)
+			}
+		}
+	})
+
+	t.Run("Duplicates", func(t *testing.T) {
+		h := NewHyperLogLog(p)
+		for range 10 {
+			for i := range 500 {
+				h.Add(item("user", i))
+			}
+		}
+		if e := relativeError(h.Count(), 500); e > 0.01 {
+			t.Fatalf("expected about 500 distinct items, got %d", h.Count())
+		}
+	})
+
+	t.Run("Sparse to dense", func(t *testing.T) {
+		h := NewHyperLogLog(p)
+		if h.Count() != 0 {
+			t.Fatalf("expected empty sketch to count 0, got %d", h.Count())
+		}
+
+		// registers take 16KB, so up to 4096 sparse entries fit
+		for i := range 4000 {
+			h.Add(item("user", i))
+		}
+		if !h.IsSparse() {
+			t.Fatal("expected sketch to stay sparse")
+		}
+		if e := relativeError(h.Count(), 4000); e > 0.005 {
+			t.Fatalf("expected sparse estimate to be close to exact, got %d", h.Count())
+		}
+
+		for i := 4000; i < 5000; i++ {
+			h.Add(item("user", i))
+		}
+		h.Count()
+		if h.IsSparse() {
+			t.Fatal("expected sketch to become dense")
+		}
+		if e := relativeError(h.Count(), 5000); e > bound {
+			t.Fatalf("expected about 5000 items after conversion, got %d", h.Count())
+		}
+
+		h.Reset()
+		if !h.IsSparse() || h.Count() != 0 {
+			t.Fatal("expected reset sketch to be empty and sparse")
+		}
+	})
+
+	t.Run("Precision", func(t *testing.T) {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("expected panic for precision 3")
+			}
+		}()
+		NewHyperLogLog(3)
+	})
+}
+
+func TestHyperLogLogMerge(t *testing.T) {
+	const p = 12
+
+	// sizes of the two halves, 100 items stay sparse and 50000 make the sketch dense
+	cases := []struct {
+		name string
+		a, b int
+	}{
+		{name: "sparse+sparse", a: 100, b: 100},
+		{name: "sparse+dense", a: 100, b: 50000},
+		{name: "dense+sparse", a: 50000, b: 100},
+		{name: "dense+dense", a: 50000, b: 50000},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b, union := NewHyperLogLog(p), NewHyperLogLog(p), NewHyperLogLog(p)
+			// half of b overlaps with a
+			for i := range tc.a {
+				a.Add(item("user", i))
+				union.Add(item("user", i))
+			}
+			for i := range tc.b {
+				b.Add(item("user", i+tc.a/2))
+				union.Add(item("user", i+tc.a/2))
+			}
+
+			if err := a.Merge(b); err != nil {
+				t.Fatalf("merge: %v", err)
+			}
+			if a.Count() != union.Count() {
+				t.Fatalf("expected merged count %d to equal count of the union %d", a.Count(), union.Count())
+			}
+		})
+	}
+
+	if err := NewHyperLogLog(12).Merge(NewHyperLogLog(14)); !errors.Is(err, ErrPrecision) {
+		t.Fatalf("expected ErrPrecision, got %v", err)
+	}
+}
+
+func BenchmarkHyperLogLog(b *testing.B) {
+	items := make([][]byte, 1<<16)
+	for i := range items {
+		items[i] = item("user", i)
+	}
+
+	h := NewHyperLogLog(14)
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		h.Add(items[i&(len(items)-1)])
+	}
+}